@@ -0,0 +1,98 @@
+package dictionary
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"net/netip"
+)
+
+// AddrKey is a convenience type for using IP addresses as keys in a
+// dictionary. Create one by converting a netip.Addr.
+type AddrKey netip.Addr
+
+// AddrPortKey is a convenience type for using IP address and port pairs as
+// keys in a dictionary. Create one by converting a netip.AddrPort.
+type AddrPortKey netip.AddrPort
+
+// hashAddr writes everything that makes addresses unequal: the 16 bytes of
+// the address, whether it is IPv4, and the zone.
+func hashAddr(h *maphash.Hash, a netip.Addr) {
+	b := a.As16()
+	h.Write(b[:])
+	if a.Is4() {
+		h.WriteByte(4)
+	} else {
+		h.WriteByte(6)
+	}
+	h.WriteString(a.Zone())
+}
+
+// unseeded is used for Hash, which has no seed to use.
+var unseeded = maphash.MakeSeed()
+
+// Hash generates a hash of the address. The seed is chosen when the program
+// starts, so it is not stable across runs.
+func (a AddrKey) Hash() uint32 {
+	return fold(a.HashSeed(unseeded))
+}
+
+// HashSeed generates a hash of the address using hash/maphash.
+func (a AddrKey) HashSeed(seed maphash.Seed) uint64 {
+	var h maphash.Hash
+	h.SetSeed(seed)
+	hashAddr(&h, netip.Addr(a))
+	return h.Sum64()
+}
+
+// Equal reports whether v, which must also be an AddrKey, is the same address.
+func (a AddrKey) Equal(v interface{}) bool {
+	return netip.Addr(a) == netip.Addr(v.(AddrKey))
+}
+
+// Less reports whether a sorts before v, which must also be an AddrKey.
+func (a AddrKey) Less(v Hasher) bool {
+	return netip.Addr(a).Less(netip.Addr(v.(AddrKey)))
+}
+
+// String returns the string form of the address.
+func (a AddrKey) String() string {
+	return netip.Addr(a).String()
+}
+
+// Hash generates a hash of the address and port. The seed is chosen when the
+// program starts, so it is not stable across runs.
+func (a AddrPortKey) Hash() uint32 {
+	return fold(a.HashSeed(unseeded))
+}
+
+// HashSeed generates a hash of the address and port using hash/maphash.
+func (a AddrPortKey) HashSeed(seed maphash.Seed) uint64 {
+	var h maphash.Hash
+	h.SetSeed(seed)
+	ap := netip.AddrPort(a)
+	hashAddr(&h, ap.Addr())
+	var port [2]byte
+	binary.BigEndian.PutUint16(port[:], ap.Port())
+	h.Write(port[:])
+	return h.Sum64()
+}
+
+// Equal reports whether v, which must also be an AddrPortKey, is the same
+// address and port.
+func (a AddrPortKey) Equal(v interface{}) bool {
+	return netip.AddrPort(a) == netip.AddrPort(v.(AddrPortKey))
+}
+
+// Less reports whether a sorts before v, which must also be an AddrPortKey.
+func (a AddrPortKey) Less(v Hasher) bool {
+	x, y := netip.AddrPort(a), netip.AddrPort(v.(AddrPortKey))
+	if x.Addr() != y.Addr() {
+		return x.Addr().Less(y.Addr())
+	}
+	return x.Port() < y.Port()
+}
+
+// String returns the string form of the address and port.
+func (a AddrPortKey) String() string {
+	return netip.AddrPort(a).String()
+}
@@ -0,0 +1,56 @@
+package dictionary
+
+// arena allocates items in slabs, rather than one at a time. Removed items
+// are kept on a free list and reused, as a slab cannot be freed until none of
+// its items are in use.
+type arena struct {
+	slabSize int
+	// the unused part of the current slab.
+	slab []item
+	free []*item
+}
+
+// the slab size used if none is given.
+const defaultSlabSize = 1024
+
+// WithArena allocates entries in slabs of n at a time, rather than one at a
+// time, and reuses the entries that are removed. For dictionaries with
+// millions of long-lived entries, this leaves the garbage collector far fewer
+// objects to track. The slabs are released all at once by Clear. If n is zero
+// or less, a default size is used. It takes precedence over WithItemPool.
+func WithArena(n int) OptionsFunc {
+	return func(d *Dictionary) {
+		if n <= 0 {
+			n = defaultSlabSize
+		}
+		d.arena = &arena{slabSize: n}
+	}
+}
+
+func (a *arena) alloc() *item {
+	if n := len(a.free); n > 0 {
+		i := a.free[n-1]
+		a.free[n-1] = nil
+		a.free = a.free[:n-1]
+		return i
+	}
+	if len(a.slab) == 0 {
+		a.slab = make([]item, a.slabSize)
+	}
+	i := &a.slab[0]
+	a.slab = a.slab[1:]
+	return i
+}
+
+func (a *arena) release(i *item) {
+	// do not keep the key and value alive.
+	*i = item{}
+	a.free = append(a.free, i)
+}
+
+// reset drops the slabs, so they can be collected once none of their items
+// are in use.
+func (a *arena) reset() {
+	a.slab = nil
+	a.free = nil
+}
@@ -0,0 +1,42 @@
+package dictionary
+
+import (
+	"fmt"
+	"hash/maphash"
+	"reflect"
+)
+
+// ComparableKey wraps any comparable Go value so it can be used as a key. It
+// is created by AutoKey. Keys are hashed with hash/maphash and compared
+// with ==, so small structs and other values can be used without
+// implementing Hasher.
+type ComparableKey struct {
+	Value interface{}
+}
+
+// AutoKey wraps v, which must be comparable, in a ComparableKey. It panics if
+// v is nil or its type is not comparable. Values holding interfaces with
+// uncomparable dynamic types panic when hashed, as with built-in maps.
+func AutoKey(v interface{}) Hasher {
+	if v == nil || !reflect.TypeOf(v).Comparable() {
+		panic(fmt.Sprintf("dictionary: AutoKey of uncomparable type %T", v))
+	}
+	return ComparableKey{Value: v}
+}
+
+// Hash generates a hash of the value. The seed is chosen when the program
+// starts, so it is not stable across runs.
+func (c ComparableKey) Hash() uint32 {
+	return fold(c.HashSeed(unseeded))
+}
+
+// HashSeed generates a hash of the value using hash/maphash.
+func (c ComparableKey) HashSeed(seed maphash.Seed) uint64 {
+	return maphash.Comparable(seed, c.Value)
+}
+
+// Equal reports whether v, which must also be a ComparableKey, holds an
+// equal value.
+func (c ComparableKey) Equal(v interface{}) bool {
+	return c.Value == v.(ComparableKey).Value
+}
@@ -0,0 +1,531 @@
+package dictionary_test
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/bakins/dictionary"
+	"github.com/stretchr/testify/require"
+)
+
+// sameKey has the same hash for every key.
+type sameKey int
+
+func (k sameKey) Hash() uint32 {
+	return 1
+}
+
+func (k sameKey) Equal(v interface{}) bool {
+	return k == v.(sameKey)
+}
+
+var backends = map[string]dictionary.Backend{
+	"chaining":        dictionary.Chaining,
+	"open addressing": dictionary.OpenAddressing,
+	"swiss table":     dictionary.SwissTable,
+	"cuckoo":          dictionary.Cuckoo,
+	"go map":          dictionary.GoMap,
+}
+
+func TestBackends(t *testing.T) {
+	for name, b := range backends {
+		t.Run(name, func(t *testing.T) {
+			d := dictionary.New(dictionary.WithBackend(b), dictionary.SetBuckets(7))
+
+			// n and -n have the same hash, so there are plenty of collisions.
+			for n := -500; n < 500; n++ {
+				d.Set(intKey(n), n)
+			}
+			require.Equal(t, 1000, d.Len(), "unexpected length")
+			require.NoError(t, d.CheckInvariants())
+
+			for n := -500; n < 500; n++ {
+				v, ok := d.Get(intKey(n))
+				require.Equal(t, true, ok, "should have found key")
+				require.Equal(t, n, v.(int), "unexpected value")
+			}
+
+			for n := -500; n < 500; n += 2 {
+				_, ok := d.Delete(intKey(n))
+				require.Equal(t, true, ok, "should have deleted key")
+			}
+			require.Equal(t, 500, d.Len(), "unexpected length")
+			require.NoError(t, d.CheckInvariants())
+
+			for n := -500; n < 500; n++ {
+				require.Equal(t, n%2 != 0, d.Contains(intKey(n)), "unexpected key %d", n)
+			}
+
+			// removing every entry while iterating must not skip any.
+			seen := 0
+			require.NoError(t, d.Each(func(k dictionary.Hasher, _ interface{}) error {
+				seen++
+				d.Delete(k)
+				return nil
+			}))
+			require.Equal(t, 500, seen, "unexpected number of entries")
+			require.Equal(t, 0, d.Len(), "unexpected length")
+
+			// cuckoo hashing only has two slots for a hash.
+			s := dictionary.New(dictionary.WithBackend(b))
+			for n := 0; n < 20; n++ {
+				s.Set(sameKey(n), n)
+			}
+			for n := 0; n < 20; n++ {
+				v, ok := s.Delete(sameKey(n))
+				require.Equal(t, true, ok, "should have deleted key")
+				require.Equal(t, n, v.(int), "unexpected value")
+			}
+			require.Equal(t, 0, s.Len(), "unexpected length")
+
+			d.Set(intKey(1), 1)
+			it := d.Iterator()
+			require.Equal(t, true, it.Next(), "should have an entry")
+			require.Equal(t, intKey(1), it.Key())
+			require.Equal(t, false, it.Next(), "should have no more entries")
+		})
+	}
+}
+
+func TestChainLengthWarning(t *testing.T) {
+	for name, b := range backends {
+		if b == dictionary.GoMap {
+			// the map's chains cannot be seen.
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			var warnings, longest int
+			buckets := map[int]bool{}
+			d := dictionary.New(dictionary.WithBackend(b), dictionary.WithChainLengthWarning(2,
+				func(bucket, length int, key dictionary.Hasher) {
+					require.Greater(t, length, 2)
+					require.IsType(t, sameKey(0), key)
+					warnings++
+					longest = max(longest, length)
+					buckets[bucket] = true
+				},
+			))
+
+			d.Set(sameKey(0), 0)
+			require.Equal(t, 0, warnings, "should not have warned")
+			for n := 1; n < 20; n++ {
+				d.Set(sameKey(n), n)
+			}
+			require.Greater(t, warnings, 0, "should have warned")
+			// cuckoo hashing may kick an older key into the stash, so the
+			// longest chain is not always found by adding to it.
+			require.LessOrEqual(t, longest, d.Stats().MaxChain, "unexpected longest chain")
+			require.Equal(t, 1, len(buckets), "every key should be in the same bucket")
+		})
+	}
+
+	_, err := dictionary.NewWithError(dictionary.WithChainLengthWarning(0, func(int, int, dictionary.Hasher) {}))
+	require.ErrorIs(t, err, dictionary.ErrInvalidOption)
+}
+
+// sortedBucket is a Bucket kept sorted by hash, for testing WithBucketFactory.
+type sortedBucket struct {
+	entries []dictionary.BucketEntry
+}
+
+func (b *sortedBucket) search(hash uint64) int {
+	return sort.Search(len(b.entries), func(n int) bool { return b.entries[n].Hash() >= hash })
+}
+
+func (b *sortedBucket) Find(key dictionary.Hasher, hash uint64) dictionary.BucketEntry {
+	for n := b.search(hash); n < len(b.entries) && b.entries[n].Hash() == hash; n++ {
+		if key.Equal(b.entries[n].Key()) {
+			return b.entries[n]
+		}
+	}
+	return nil
+}
+
+func (b *sortedBucket) Insert(e dictionary.BucketEntry) {
+	n := b.search(e.Hash())
+	b.entries = append(b.entries, nil)
+	copy(b.entries[n+1:], b.entries[n:])
+	b.entries[n] = e
+}
+
+func (b *sortedBucket) Delete(e dictionary.BucketEntry) bool {
+	for n := b.search(e.Hash()); n < len(b.entries) && b.entries[n].Hash() == e.Hash(); n++ {
+		if b.entries[n] == e {
+			b.entries = append(b.entries[:n], b.entries[n+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (b *sortedBucket) Len() int {
+	return len(b.entries)
+}
+
+func (b *sortedBucket) Each(f func(dictionary.BucketEntry) bool) {
+	for _, e := range b.entries {
+		if !f(e) {
+			return
+		}
+	}
+}
+
+func TestBucketFactory(t *testing.T) {
+	var buckets int
+	d := dictionary.New(dictionary.SetBuckets(3), dictionary.WithBucketFactory(func() dictionary.Bucket {
+		buckets++
+		return &sortedBucket{}
+	}))
+	require.Equal(t, 3, buckets, "unexpected number of buckets")
+
+	// n and -n have the same hash, and there are enough keys per bucket that
+	// the built in buckets would become trees.
+	for n := -50; n < 50; n++ {
+		d.Set(intKey(n), n)
+	}
+	require.Equal(t, 100, d.Len(), "unexpected length")
+	require.NoError(t, d.CheckInvariants())
+	for n := -50; n < 50; n += 2 {
+		_, ok := d.Delete(intKey(n))
+		require.Equal(t, true, ok, "should have deleted key")
+	}
+	for n := -50; n < 50; n++ {
+		require.Equal(t, n%2 != 0, d.Contains(intKey(n)), "unexpected key %d", n)
+	}
+	require.Equal(t, 50, len(d.Keys()), "unexpected number of keys")
+
+	_, err := dictionary.NewWithError(dictionary.WithBackend(dictionary.SwissTable), dictionary.WithBucketFactory(func() dictionary.Bucket {
+		return &sortedBucket{}
+	}))
+	require.ErrorIs(t, err, dictionary.ErrInvalidOption)
+}
+
+func TestGoMap(t *testing.T) {
+	d := dictionary.New(dictionary.WithBackend(dictionary.GoMap))
+
+	// keys whose Equal is not == use their MapKey.
+	d.Set(dictionary.BytesKey("a"), 1)
+	d.Set(dictionary.StringKey("a"), 2)
+	d.Set(dictionary.FoldedStringKey("Host"), 3)
+	require.Equal(t, 3, d.Len(), "unexpected length")
+	v, ok := d.Get(dictionary.BytesKey("a"))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, 1, v.(int), "unexpected value")
+	v, _ = d.Get(dictionary.FoldedStringKey("HOST"))
+	require.Equal(t, 3, v.(int), "unexpected value")
+
+	c := dictionary.New(dictionary.WithBackend(dictionary.GoMap))
+	c.Set(c.CachedKey(dictionary.StringKey("k")), 1)
+	require.Equal(t, true, c.Contains(c.CachedKey(dictionary.StringKey("k"))), "should have found key")
+
+	// composite keys are slices, so they use the map keys of their parts.
+	m := dictionary.New(dictionary.WithBackend(dictionary.GoMap))
+	m.Set(dictionary.NewCompositeKey(dictionary.StringKey("a"), dictionary.BytesKey("b")), 1)
+	m.Set(dictionary.NewCompositeKey(dictionary.StringKey("a"), dictionary.StringKey("b")), 2)
+	m.Set(dictionary.NewCompositeKey(dictionary.StringKey("a"), dictionary.BytesKey("b")), 3)
+	require.Equal(t, 2, m.Len(), "unexpected length")
+	v, _ = m.Get(dictionary.NewCompositeKey(dictionary.StringKey("a"), dictionary.BytesKey("b")))
+	require.Equal(t, 3, v.(int), "unexpected value")
+
+	// keys that cannot be map keys are chained by hash.
+	for n := 0; n < 10; n++ {
+		m.Set(sliceKey{byte(n)}, n)
+	}
+	require.Equal(t, 12, m.Len(), "unexpected length")
+	v, ok = m.Get(sliceKey{4})
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, 4, v.(int), "unexpected value")
+	_, ok = m.Delete(sliceKey{4})
+	require.Equal(t, true, ok, "should have deleted key")
+	require.Equal(t, false, m.Contains(sliceKey{4}), "should not have found key")
+	require.Equal(t, 11, len(m.Keys()), "unexpected number of keys")
+}
+
+// sliceKey is not comparable, and does not implement MapKeyer.
+type sliceKey []byte
+
+func (k sliceKey) Hash() uint32 {
+	return uint32(len(k)) // every key collides
+}
+
+func (k sliceKey) Equal(v interface{}) bool {
+	return string(k) == string(v.(sliceKey))
+}
+
+func TestTreeify(t *testing.T) {
+	// every key lands in the same bucket with the same hash, so the bucket
+	// is converted to a tree ordered by key.
+	same := func(string) uint64 { return 1 }
+	d := dictionary.New(dictionary.SetBuckets(1), dictionary.WithStringHash(same))
+
+	for n := 0; n < 100; n++ {
+		d.Set(dictionary.StringKey(strconv.Itoa(n)), n)
+	}
+	require.Equal(t, 100, d.Len(), "unexpected length")
+
+	for n := 0; n < 100; n++ {
+		v, ok := d.Get(dictionary.StringKey(strconv.Itoa(n)))
+		require.Equal(t, true, ok, "should have found key")
+		require.Equal(t, n, v.(int), "unexpected value")
+	}
+	require.Equal(t, false, d.Contains(dictionary.StringKey("100")), "should not have found key")
+
+	// shrink back below the threshold, where the bucket is a list again.
+	for n := 0; n < 95; n++ {
+		_, ok := d.Delete(dictionary.StringKey(strconv.Itoa(n)))
+		require.Equal(t, true, ok, "should have deleted key")
+	}
+	require.Equal(t, map[string]interface{}{"95": 95, "96": 96, "97": 97, "98": 98, "99": 99}, keySet(d))
+
+	// keys that are not Ordered cannot go in a tree.
+	i := dictionary.New(dictionary.SetBuckets(1))
+	for n := 0; n < 100; n++ {
+		i.Set(sameKey(n), n)
+	}
+	require.Equal(t, 100, i.Len(), "unexpected length")
+	require.Equal(t, true, i.Contains(sameKey(42)), "should have found key")
+}
+
+func BenchmarkSet(b *testing.B) {
+	for name, backend := range backends {
+		b.Run(name, func(b *testing.B) {
+			d := dictionary.New(dictionary.WithBackend(backend), dictionary.SetBuckets(1024))
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				d.Set(dictionary.Int64Key(n%4096), n)
+			}
+		})
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	for name, backend := range backends {
+		b.Run(name, func(b *testing.B) {
+			d := dictionary.New(dictionary.WithBackend(backend), dictionary.SetBuckets(1024))
+			for n := 0; n < 4096; n++ {
+				d.Set(dictionary.Int64Key(n), n)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				d.Get(dictionary.Int64Key(n % 4096))
+			}
+		})
+	}
+}
+
+// keys converted to a Hasher once, so the benchmarks only measure lookups.
+func BenchmarkGetMiss(b *testing.B) {
+	options := map[string][]dictionary.OptionsFunc{
+		"plain": nil,
+		"bloom": {dictionary.WithBloomFilter(10000, 0.01)},
+	}
+	for name, opts := range options {
+		b.Run(name, func(b *testing.B) {
+			d := dictionary.New(opts...)
+			for n := 0; n < 10000; n++ {
+				d.Set(dictionary.Int64Key(n), n)
+			}
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				d.Get(dictionary.Int64Key(10000 + n%10000))
+			}
+		})
+	}
+}
+
+func benchmarkKeys(n int) []dictionary.Hasher {
+	keys := make([]dictionary.Hasher, n)
+	for i := range keys {
+		keys[i] = dictionary.StringKey(strconv.Itoa(i))
+	}
+	return keys
+}
+
+func BenchmarkGetValue(b *testing.B) {
+	for name, backend := range backends {
+		b.Run(name, func(b *testing.B) {
+			keys := benchmarkKeys(4096)
+			d := dictionary.New(dictionary.WithBackend(backend), dictionary.SetBuckets(1024))
+			for n, k := range keys {
+				d.Set(k, n)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				dictionary.GetValue[int](d, keys[n%len(keys)])
+			}
+		})
+	}
+}
+
+func TestGetAllocs(t *testing.T) {
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			keys := benchmarkKeys(100)
+			d := dictionary.New(dictionary.WithBackend(backend))
+			for n, k := range keys {
+				d.Set(k, n)
+			}
+			missing := dictionary.Hasher(dictionary.StringKey("missing"))
+
+			allocs := testing.AllocsPerRun(100, func() {
+				for n, k := range keys {
+					v, ok := dictionary.GetValue[int](d, k)
+					if !ok || v != n {
+						t.Fatalf("unexpected value %v for %v", v, k)
+					}
+				}
+				d.Get(missing)
+				d.Contains(missing)
+			})
+			require.Equal(t, float64(0), allocs, "lookups should not allocate")
+
+			_, ok := dictionary.GetValue[string](d, keys[0])
+			require.Equal(t, false, ok, "values of another type should not be returned")
+		})
+	}
+}
+
+func TestReserve(t *testing.T) {
+	for name, b := range backends {
+		t.Run(name, func(t *testing.T) {
+			d := dictionary.New(dictionary.WithBackend(b), dictionary.WithCapacity(100))
+			for n := 0; n < 100; n++ {
+				d.Set(intKey(n), n)
+			}
+
+			d.Reserve(10000)
+			for n := 100; n < 10000; n++ {
+				d.Set(intKey(n), n)
+			}
+			require.Equal(t, 10000, d.Len(), "unexpected length")
+
+			for n := 0; n < 10000; n++ {
+				v, ok := d.Get(intKey(n))
+				require.Equal(t, true, ok, "should have found key")
+				require.Equal(t, n, v.(int), "unexpected value")
+			}
+		})
+	}
+}
+
+func TestShrink(t *testing.T) {
+	for name, b := range backends {
+		t.Run(name, func(t *testing.T) {
+			for _, auto := range []bool{false, true} {
+				options := []dictionary.OptionsFunc{dictionary.WithBackend(b), dictionary.WithCapacity(10000)}
+				if auto {
+					options = append(options, dictionary.WithAutoShrink(0.25))
+				}
+				d := dictionary.New(options...)
+				for n := 0; n < 10000; n++ {
+					d.Set(intKey(n), n)
+				}
+
+				// remove entries while iterating, which may shrink the table.
+				require.NoError(t, d.Each(func(k dictionary.Hasher, _ interface{}) error {
+					if k.(intKey) >= 10 {
+						d.Delete(k)
+					}
+					return nil
+				}))
+				d.Shrink()
+
+				require.Equal(t, 10, d.Len(), "unexpected length")
+				for n := 0; n < 10; n++ {
+					v, ok := d.Get(intKey(n))
+					require.Equal(t, true, ok, "should have found key")
+					require.Equal(t, n, v.(int), "unexpected value")
+				}
+			}
+		})
+	}
+}
+
+func TestRehash(t *testing.T) {
+	for name, b := range backends {
+		t.Run(name, func(t *testing.T) {
+			d := dictionary.New(dictionary.WithBackend(b))
+			for n := 0; n < 1000; n++ {
+				d.Set(intKey(n), n)
+			}
+
+			for _, buckets := range []uint32{1, 0, 1021} {
+				d.Rehash(buckets)
+				require.Equal(t, 1000, d.Len(), "unexpected length")
+				for n := 0; n < 1000; n++ {
+					v, ok := d.Get(intKey(n))
+					require.Equal(t, true, ok, "should have found key")
+					require.Equal(t, n, v.(int), "unexpected value")
+				}
+			}
+		})
+	}
+}
+
+func TestIncrementalResize(t *testing.T) {
+	for name, b := range backends {
+		t.Run(name, func(t *testing.T) {
+			d := dictionary.New(dictionary.WithBackend(b), dictionary.WithIncrementalResize())
+			for n := 0; n < 10000; n++ {
+				d.Set(intKey(n), n)
+				// some entries are in the old table and some in the new one.
+				if n%1000 == 0 {
+					require.Equal(t, n+1, len(d.Keys()), "unexpected number of keys")
+					require.Equal(t, true, d.Contains(intKey(n/2)), "should have found key")
+				}
+			}
+
+			for n := 0; n < 10000; n += 2 {
+				_, ok := d.Delete(intKey(n))
+				require.Equal(t, true, ok, "should have deleted key")
+			}
+			require.Equal(t, 5000, d.Len(), "unexpected length")
+			require.NoError(t, d.CheckInvariants())
+
+			seen := make(map[intKey]bool)
+			require.NoError(t, d.Each(func(k dictionary.Hasher, v interface{}) error {
+				require.Equal(t, false, seen[k.(intKey)], "should only see a key once")
+				seen[k.(intKey)] = true
+				require.Equal(t, int(k.(intKey)), v.(int), "unexpected value")
+				return nil
+			}))
+			require.Equal(t, 5000, len(seen), "unexpected number of keys")
+		})
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	d := dictionary.New()
+	for n := 0; n < 1000; n++ {
+		d.Set(dictionary.Int64Key(n), n)
+	}
+	f := d.Freeze()
+	d.Set(dictionary.Int64Key(1000), 1000)
+
+	require.Equal(t, 1000, f.Len(), "unexpected length")
+	for n := 0; n < 1000; n++ {
+		v, ok := f.Get(dictionary.Int64Key(n))
+		require.Equal(t, true, ok, "key should be present")
+		require.Equal(t, n, v.(int), "unexpected value")
+	}
+	require.Equal(t, false, f.Contains(dictionary.Int64Key(1000)), "later changes should not be seen")
+	require.Len(t, f.Keys(), 1000)
+
+	// keys that cannot be told apart by their hash.
+	d = dictionary.New()
+	for n := 0; n < 10; n++ {
+		d.Set(sameKey(n), n)
+	}
+	f = d.Freeze()
+	for n := 0; n < 10; n++ {
+		v, ok := f.Get(sameKey(n))
+		require.Equal(t, true, ok, "key should be present")
+		require.Equal(t, n, v.(int), "unexpected value")
+	}
+	require.Equal(t, false, f.Contains(sameKey(10)), "key should not be present")
+
+	require.Equal(t, 0, dictionary.New().Freeze().Len(), "unexpected length")
+}
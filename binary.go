@@ -0,0 +1,80 @@
+package dictionary
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+func init() {
+	// keys and values are stored as interfaces, so gob needs to know about
+	// the concrete types. Users must register their own key and value types.
+	gob.Register(StringKey(""))
+	gob.Register(BytesKey(nil))
+	gob.Register(Int64Key(0))
+	gob.Register(Uint64Key(0))
+	gob.Register(Float64Key(0))
+}
+
+// binaryDictionary is the gob encoded form of a dictionary.
+type binaryDictionary struct {
+	Buckets uint32
+	Items   []Item
+	// the entries, instead of Items, if the keys were encoded with a
+	// KeyCodec.
+	Coded []codedItem
+}
+
+// MarshalBinary encodes the dictionary, including its number of buckets,
+// using encoding/gob. StringKey, BytesKey, Int64Key, Uint64Key and Float64Key
+// are registered by this package; other key and value types must be
+// registered with gob.Register, unless WithKeyCodec is set.
+func (d *Dictionary) MarshalBinary() ([]byte, error) {
+	b := binaryDictionary{Buckets: d.numBuckets}
+	var err error
+	d.walk(func(i *item) bool {
+		if d.keyCodec == nil {
+			b.Items = append(b.Items, Item{Key: i.key, Value: i.value})
+			return true
+		}
+		var c codedItem
+		c, err = d.codeItem(i)
+		b.Coded = append(b.Coded, c)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&b); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a dictionary encoded by MarshalBinary. Any existing
+// entries are discarded, and the number of buckets is restored. If the keys
+// were encoded with a KeyCodec, the dictionary must have been created with
+// the same one.
+func (d *Dictionary) UnmarshalBinary(data []byte) error {
+	var b binaryDictionary
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&b); err != nil {
+		return err
+	}
+	if len(b.Coded) > 0 && d.keyCodec == nil {
+		return ErrNoKeyCodec
+	}
+
+	d.clear()
+	d.numBuckets = b.Buckets
+	d.init()
+	for _, i := range b.Items {
+		d.Set(i.Key, i.Value)
+	}
+	for _, c := range b.Coded {
+		if err := d.setCoded(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
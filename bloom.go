@@ -0,0 +1,101 @@
+package dictionary
+
+import "math"
+
+// WithBloomFilter keeps a Bloom filter of the keys, sized for expectedItems
+// keys with a false positive rate of fpRate. Lookups of keys that are
+// certainly not present, such as by Get and Contains, then return after
+// checking a few bits, without searching the store, which helps workloads
+// where most lookups miss. Keys are still hashed.
+//
+// A Bloom filter cannot forget a key, so it is rebuilt once many keys have
+// been removed, or once there are many more keys than expected.
+func WithBloomFilter(expectedItems int, fpRate float64) OptionsFunc {
+	return func(d *Dictionary) {
+		d.bloomItems = expectedItems
+		d.bloomRate = fpRate
+	}
+}
+
+// bloomFilter is a Bloom filter of item hashes. Each hash sets k bits, derived
+// from two hashes by double hashing.
+type bloomFilter struct {
+	bits []uint64
+	// the number of bits, and bits set for each hash.
+	m, k uint64
+	// the hashes the filter was sized for, the hashes added, and the items
+	// removed since it was built.
+	capacity, added, removed int
+	rate                     float64
+}
+
+// newBloomFilter sizes a filter for n hashes, using the standard formulas for
+// the number of bits and hash functions that give a false positive rate p.
+func newBloomFilter(n int, p float64) *bloomFilter {
+	n = max(n, 1)
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	return &bloomFilter{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        max(k, 1),
+		capacity: n,
+		rate:     p,
+	}
+}
+
+// the two hashes for double hashing. The second is odd, so it is never zero.
+func bloomHashes(h uint64) (uint64, uint64) {
+	return mix(h), mix(h^0x9e3779b97f4a7c15) | 1
+}
+
+func (b *bloomFilter) add(h uint64) {
+	h1, h2 := bloomHashes(h)
+	for n := uint64(0); n < b.k; n++ {
+		bit := (h1 + n*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+	b.added++
+}
+
+// mayContain returns false if h was certainly never added.
+func (b *bloomFilter) mayContain(h uint64) bool {
+	h1, h2 := bloomHashes(h)
+	for n := uint64(0); n < b.k; n++ {
+		bit := (h1 + n*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// stale reports whether the filter should be rebuilt, because so many items
+// have been removed, or so many more added than it was sized for, that false
+// positives have become common.
+func (b *bloomFilter) stale() bool {
+	return b.removed > b.capacity/2 || b.added > 2*b.capacity
+}
+
+// helper to create the Bloom filter, if WithBloomFilter is set with valid
+// arguments.
+func (d *Dictionary) newBloomFilter() *bloomFilter {
+	if d.bloomItems <= 0 || d.bloomRate <= 0 || d.bloomRate >= 1 {
+		return nil
+	}
+	return newBloomFilter(d.bloomItems, d.bloomRate)
+}
+
+// helper to rebuild the Bloom filter from the items in the store, sized for
+// at least twice as many as there are now.
+func (d *Dictionary) rebuildBloom() {
+	b := newBloomFilter(max(d.bloomItems, 2*d.count), d.bloom.rate)
+	// the cursor is used rather than walk, as this is called while an item
+	// is being removed, and expired items may as well be included.
+	c := d.store.cursor()
+	for i := c.next(); i != nil; i = c.next() {
+		b.add(i.hash)
+	}
+	d.bloom = b
+}
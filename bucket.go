@@ -0,0 +1,92 @@
+package dictionary
+
+type (
+	// Bucket holds the entries of a Chaining dictionary that hash to the
+	// same bucket. The built in buckets are slices that become trees when
+	// they grow long; WithBucketFactory replaces them with another
+	// structure, such as a sorted slice or a skip list.
+	//
+	// A dictionary never inserts an entry whose key is already present, and
+	// removes entries by identity, so a Bucket only has to compare keys in
+	// Find.
+	Bucket interface {
+		// Find returns the entry whose key is equal to key, or nil if
+		// there is none. hash is the hash of key, and entries with a
+		// different Hash cannot match.
+		Find(key Hasher, hash uint64) BucketEntry
+		// Insert adds an entry.
+		Insert(e BucketEntry)
+		// Delete removes an entry, comparing with ==. It returns false if
+		// the entry was not found.
+		Delete(e BucketEntry) bool
+		// Len returns the number of entries.
+		Len() int
+		// Each calls f on each entry until f returns false. f does not
+		// change the bucket.
+		Each(f func(e BucketEntry) bool)
+	}
+
+	// BucketEntry is an entry held by a Bucket. Its key and hash do not
+	// change while it is in the bucket.
+	BucketEntry interface {
+		// Key returns the key of the entry.
+		Key() Hasher
+		// Hash returns the hash of the key, as used by the dictionary.
+		Hash() uint64
+	}
+
+	// BucketFactory creates an empty Bucket.
+	BucketFactory func() Bucket
+)
+
+// WithBucketFactory sets the function used to create each bucket of a
+// Chaining dictionary. Other backends ignore it, and NewWithError reports it
+// as invalid.
+func WithBucketFactory(f BucketFactory) OptionsFunc {
+	return func(d *Dictionary) {
+		d.bucketFactory = f
+	}
+}
+
+// Key returns the key of the item, so it can be used as a BucketEntry.
+func (i *item) Key() Hasher {
+	return i.key
+}
+
+// Hash returns the hash of the item's key.
+func (i *item) Hash() uint64 {
+	return i.hash
+}
+
+// customBucket adapts a Bucket to the bucket interface. Every BucketEntry
+// given to it is an item, so those it returns are as well.
+type customBucket struct {
+	b Bucket
+}
+
+func (c customBucket) find(key Hasher, h uint64) *item {
+	if e := c.b.Find(key, h); e != nil {
+		return e.(*item)
+	}
+	return nil
+}
+
+func (c customBucket) insert(i *item) {
+	c.b.Insert(i)
+}
+
+func (c customBucket) delete(i *item) bool {
+	return c.b.Delete(i)
+}
+
+func (c customBucket) len() int {
+	return c.b.Len()
+}
+
+func (c customBucket) appendItems(dst []*item) []*item {
+	c.b.Each(func(e BucketEntry) bool {
+		dst = append(dst, e.(*item))
+		return true
+	})
+	return dst
+}
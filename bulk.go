@@ -0,0 +1,69 @@
+package dictionary
+
+import "time"
+
+// SetMany adds all of the items to the dictionary, replacing any existing
+// values. If a key appears more than once, the last value wins. Each key is
+// hashed once, and a dictionary that grows as it fills up, such as with the
+// OpenAddressing backend or WithIncrementalResize, is grown once, before any
+// of the items are added.
+func (d *Dictionary) SetMany(items []Item) {
+	type hashedKey struct {
+		key  Hasher
+		hash uint64
+	}
+	keys := make([]hashedKey, len(items))
+	missing := 0
+	for n, i := range items {
+		key, h, found := d.lookup(i.Key)
+		keys[n] = hashedKey{key, h}
+		if found == nil {
+			missing++
+		}
+	}
+	if d.backend != Chaining || d.incremental {
+		n := d.count + missing
+		if d.maxEntries > 0 {
+			n = min(n, d.maxEntries)
+		}
+		d.Reserve(n)
+	}
+
+	for n, i := range items {
+		k := keys[n]
+		// looked up again, as an earlier item may have added the key.
+		d.setItem(k.key, k.hash, d.find(k.key, k.hash), i.Value, time.Time{}, 0)
+	}
+}
+
+// GetMany looks up each of the keys, as Get does. The returned slices are the
+// same length as keys: values holds the value for each key, and found reports
+// whether the key was present.
+func (d *Dictionary) GetMany(keys []Hasher) (values []interface{}, found []bool) {
+	values = make([]interface{}, len(keys))
+	found = make([]bool, len(keys))
+	for n, key := range keys {
+		values[n], found[n] = d.Get(key)
+	}
+	return values, found
+}
+
+// DeleteMany removes each of the keys from the dictionary. It returns the
+// number of keys that were present. With WithAutoShrink, the dictionary is
+// only shrunk once, after all of the keys are removed.
+func (d *Dictionary) DeleteMany(keys []Hasher) int {
+	shrinkFraction := d.shrinkFraction
+	d.shrinkFraction = 0
+	defer func() {
+		d.shrinkFraction = shrinkFraction
+		d.autoShrink()
+	}()
+
+	deleted := 0
+	for _, key := range keys {
+		if _, ok := d.Delete(key); ok {
+			deleted++
+		}
+	}
+	return deleted
+}
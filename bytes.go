@@ -0,0 +1,34 @@
+package dictionary
+
+import (
+	"bytes"
+	"hash/crc32"
+	"hash/maphash"
+)
+
+// BytesKey is a convenience type for using byte slices, such as binary
+// identifiers, as keys in a dictionary. Converting a []byte to a BytesKey does
+// not copy it, so lookups are cheap. The dictionary holds on to the slice of
+// keys that are added, so it must not be modified afterwards.
+type BytesKey []byte
+
+// Hash generates a hash for the bytes using crc32
+func (b BytesKey) Hash() uint32 {
+	return crc32.ChecksumIEEE(b)
+}
+
+// HashSeed generates a hash for the bytes using hash/maphash.
+func (b BytesKey) HashSeed(seed maphash.Seed) uint64 {
+	return maphash.Bytes(seed, b)
+}
+
+// Equal reports whether v, which must also be a BytesKey, holds the same
+// bytes.
+func (b BytesKey) Equal(v interface{}) bool {
+	return bytes.Equal(b, v.(BytesKey))
+}
+
+// Less reports whether b sorts before v, which must also be a BytesKey.
+func (b BytesKey) Less(v Hasher) bool {
+	return bytes.Compare(b, v.(BytesKey)) < 0
+}
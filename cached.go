@@ -0,0 +1,72 @@
+package dictionary
+
+import "hash/maphash"
+
+// CachedHasher wraps a key and remembers its seeded hash, so keys that are
+// expensive to hash, such as large CompositeKey values, are only hashed once
+// no matter how many times they are used. It is created by CachedKey or
+// Dictionary.CachedKey. A CachedHasher is never modified once created, so it
+// may be shared between goroutines.
+//
+// A CachedHasher is only equal to keys that are also wrapped, so the same
+// dictionary should not mix wrapped and unwrapped keys.
+type CachedHasher struct {
+	// Key is the wrapped key.
+	Key Hasher
+
+	seed maphash.Seed
+	hash uint64
+}
+
+// CachedKey wraps h in a CachedHasher, hashing it once with seed, as a
+// dictionary created with SetHashSeed(seed) would. Used with a dictionary
+// with another seed, the key is hashed again on every use.
+func CachedKey(h Hasher, seed maphash.Seed) Hasher {
+	return &CachedHasher{
+		Key:  h,
+		seed: seed,
+		hash: hashKey(h, seed),
+	}
+}
+
+// CachedKey wraps h in a CachedHasher that is hashed once for use with this
+// dictionary, or a Frozen copy of it. h is normalized first if the dictionary
+// has a key normalizer. Dictionaries created with the same SetHashSeed option
+// may share cached keys.
+func (d *Dictionary) CachedKey(h Hasher) Hasher {
+	if h == nil {
+		panic(ErrNilKey)
+	}
+	if d.normalizer != nil {
+		h = d.normalizer(h)
+	}
+	return CachedKey(h, d.seed)
+}
+
+// Hash returns the cached hash of the key, reduced to 32 bits.
+func (c *CachedHasher) Hash() uint32 {
+	return fold(c.hash)
+}
+
+// HashSeed returns the cached hash of the key if seed is the one it was
+// created with. Otherwise the key is hashed again, and the result is not
+// kept.
+func (c *CachedHasher) HashSeed(seed maphash.Seed) uint64 {
+	if seed == c.seed {
+		return c.hash
+	}
+	return hashKey(c.Key, seed)
+}
+
+// Equal reports whether v, which must also be a CachedHasher, wraps an equal
+// key. Keys hashed with the same seed are not compared if their hashes differ.
+func (c *CachedHasher) Equal(v interface{}) bool {
+	o := v.(*CachedHasher)
+	if c == o {
+		return true
+	}
+	if c.seed == o.seed && c.hash != o.hash {
+		return false
+	}
+	return c.Key.Equal(o.Key)
+}
@@ -0,0 +1,47 @@
+package dictionary
+
+import (
+	"reflect"
+	"time"
+)
+
+// Swap sets the value for key, like Set, and returns the previous value. The
+// second return value will be false if the key was not present, so callers can
+// release anything held by a replaced value without a separate Get.
+func (d *Dictionary) Swap(key Hasher, val interface{}) (interface{}, bool) {
+	prev, ok, _ := d.set(key, val, time.Time{}, 0)
+	return prev, ok
+}
+
+// CompareAndSwap replaces the value for key with new, but only if the key is
+// present and its value is equal to old according to eq. It reports whether
+// the value was replaced. If eq is nil, values are compared using
+// reflect.DeepEqual. Used with Lock and Unlock, it allows optimistic updates:
+// read a value without holding the lock, compute a new one, and only store it
+// if no one else has changed it meanwhile.
+func (d *Dictionary) CompareAndSwap(key Hasher, old, new interface{}, eq EqualFunc) bool {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+	_, _, i := d.lookup(key)
+	if i == nil || !eq(i.value, old) {
+		return false
+	}
+	d.replace(i, new)
+	return true
+}
+
+// CompareAndDelete removes key, but only if it is present and its value is
+// equal to old according to eq. It reports whether the key was removed. If eq
+// is nil, values are compared using reflect.DeepEqual.
+func (d *Dictionary) CompareAndDelete(key Hasher, old interface{}, eq EqualFunc) bool {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+	_, _, i := d.lookup(key)
+	if i == nil || !eq(i.value, old) {
+		return false
+	}
+	d.delete(i)
+	return true
+}
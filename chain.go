@@ -0,0 +1,250 @@
+package dictionary
+
+type (
+	// chainTable is the default store: a fixed number of buckets, each
+	// holding the items that hash to it.
+	chainTable struct {
+		buckets []bucket
+	}
+
+	// bucket holds the items of a chainTable that hash to the same bucket.
+	bucket interface {
+		// find returns the item for key, or nil if it is not present.
+		find(key Hasher, h uint64) *item
+		// insert adds an item that is known not to be present.
+		insert(i *item)
+		// delete removes the item, comparing by pointer. It returns false if
+		// the item was not found.
+		delete(i *item) bool
+		// len returns the number of items in the bucket.
+		len() int
+		// appendItems appends the items in the bucket to dst.
+		appendItems(dst []*item) []*item
+	}
+
+	// inlineBucket is a bucket backed by a slice. Unlike a linked list,
+	// adding an item does not allocate a node for it, and the items are next
+	// to each other in memory. The first few items, and their hashes, are
+	// kept in the bucket itself, so most buckets can be searched without
+	// following any pointers other than for a matching hash. The rest spill
+	// over into a slice.
+	inlineBucket struct {
+		n        int
+		hashes   [inlineItems]uint64
+		inline   [inlineItems]*item
+		overflow []*item
+	}
+)
+
+const (
+	// the number of items kept in a bucket before using the overflow slice.
+	// With a reasonable number of buckets, most hold no more than this.
+	inlineItems = 4
+	// buckets with more items than this are converted to trees, if their
+	// keys are Ordered.
+	treeifyThreshold = 8
+	// trees with this many items or fewer are converted back to inline buckets. This
+	// is lower than treeifyThreshold, so a bucket does not flip back and
+	// forth as a single item is added and removed.
+	untreeifyThreshold = 6
+)
+
+// newChainTable creates a table with n buckets, made by factory if it is set.
+func newChainTable(n uint32, factory BucketFactory) *chainTable {
+	t := &chainTable{
+		buckets: make([]bucket, n),
+	}
+	if factory != nil {
+		for i := range t.buckets {
+			t.buckets[i] = customBucket{factory()}
+		}
+		return t
+	}
+	// allocate all of the buckets at once.
+	inline := make([]inlineBucket, n)
+	for i := range t.buckets {
+		t.buckets[i] = &inline[i]
+	}
+	return t
+}
+
+func (t *chainTable) index(h uint64) int {
+	return int(h % uint64(len(t.buckets)))
+}
+
+func (t *chainTable) find(key Hasher, h uint64) *item {
+	return t.buckets[t.index(h)].find(key, h)
+}
+
+// insert adds the item to its bucket. Long chains of Ordered keys are
+// converted to a tree, so a bucket with many colliding keys is still
+// searched in O(log n) time. Buckets from a BucketFactory are left alone.
+func (t *chainTable) insert(i *item) {
+	n := t.index(i.hash)
+	b := t.buckets[n]
+	switch b.(type) {
+	case *inlineBucket:
+		b.insert(i)
+		if b.len() > treeifyThreshold {
+			if tb := newTreeBucket(b.appendItems(nil)); tb != nil {
+				t.buckets[n] = tb
+			}
+		}
+	case *treeBucket:
+		if _, ok := i.key.(Ordered); ok {
+			b.insert(i)
+			return
+		}
+		// the key cannot be placed in the tree.
+		t.buckets[n] = newInlineBucket(append(b.appendItems(nil), i))
+	default:
+		b.insert(i)
+	}
+}
+
+func (t *chainTable) delete(i *item) bool {
+	n := t.index(i.hash)
+	b := t.buckets[n]
+	if !b.delete(i) {
+		return false
+	}
+	if _, ok := b.(*treeBucket); ok && b.len() <= untreeifyThreshold {
+		t.buckets[n] = newInlineBucket(b.appendItems(nil))
+	}
+	return true
+}
+
+// the chains get longer rather than the table growing, so aim for one item
+// per bucket.
+func (t *chainTable) capacity() int {
+	return len(t.buckets)
+}
+
+func (t *chainTable) stats(s *Stats) {
+	s.Buckets += len(t.buckets)
+	for _, b := range t.buckets {
+		n := b.len()
+		if n == 0 {
+			s.EmptyBuckets++
+		}
+		s.record(n)
+	}
+}
+
+func (t *chainTable) chain(i *item) (int, int) {
+	n := t.index(i.hash)
+	return n, t.buckets[n].len()
+}
+
+func (t *chainTable) layout(f func(items []*item)) {
+	var items []*item
+	for _, b := range t.buckets {
+		items = b.appendItems(items[:0])
+		f(items)
+	}
+}
+
+func (t *chainTable) cursor() cursor {
+	return &chainCursor{t: t, bucket: -1}
+}
+
+type chainCursor struct {
+	t      *chainTable
+	bucket int
+	// the items of the current bucket not yet returned, copied out so the
+	// item last returned can be deleted.
+	items []*item
+}
+
+func (c *chainCursor) next() *item {
+	for len(c.items) == 0 {
+		if c.bucket+1 >= len(c.t.buckets) {
+			return nil
+		}
+		c.bucket++
+		c.items = c.t.buckets[c.bucket].appendItems(c.items[:0])
+	}
+	i := c.items[0]
+	c.items = c.items[1:]
+	return i
+}
+
+func newInlineBucket(items []*item) *inlineBucket {
+	b := &inlineBucket{}
+	for _, i := range items {
+		b.insert(i)
+	}
+	return b
+}
+
+func (b *inlineBucket) find(key Hasher, h uint64) *item {
+	for n := 0; n < b.n; n++ {
+		// check the hash value first. If these are not equal, then the keys cannot be equal.
+		if b.hashes[n] == h && key.Equal(b.inline[n].key) {
+			return b.inline[n]
+		}
+	}
+	for _, v := range b.overflow {
+		if v.hash == h && key.Equal(v.key) {
+			return v
+		}
+	}
+	return nil
+}
+
+func (b *inlineBucket) insert(i *item) {
+	if b.n < inlineItems {
+		b.hashes[b.n] = i.hash
+		b.inline[b.n] = i
+		b.n++
+		return
+	}
+	b.overflow = append(b.overflow, i)
+}
+
+func (b *inlineBucket) delete(i *item) bool {
+	for n := 0; n < b.n; n++ {
+		if b.inline[n] != i {
+			continue
+		}
+		copy(b.hashes[n:b.n], b.hashes[n+1:b.n])
+		copy(b.inline[n:b.n], b.inline[n+1:b.n])
+		b.n--
+		// do not keep the item alive.
+		b.inline[b.n] = nil
+		// keep the inline items full while there are any in the overflow.
+		if len(b.overflow) > 0 {
+			v := b.overflow[0]
+			b.hashes[b.n] = v.hash
+			b.inline[b.n] = v
+			b.n++
+			b.overflow = deleteItem(b.overflow, 0)
+		}
+		return true
+	}
+	for n, v := range b.overflow {
+		if v == i {
+			b.overflow = deleteItem(b.overflow, n)
+			return true
+		}
+	}
+	return false
+}
+
+// deleteItem removes the n'th item from items, keeping the rest in order.
+func deleteItem(items []*item, n int) []*item {
+	last := len(items) - 1
+	copy(items[n:], items[n+1:])
+	// do not keep the item alive.
+	items[last] = nil
+	return items[:last]
+}
+
+func (b *inlineBucket) len() int {
+	return b.n + len(b.overflow)
+}
+
+func (b *inlineBucket) appendItems(dst []*item) []*item {
+	dst = append(dst, b.inline[:b.n]...)
+	return append(dst, b.overflow...)
+}
@@ -0,0 +1,30 @@
+package dictionary
+
+// ChainWarningFunc is called by a dictionary created with
+// WithChainLengthWarning when an entry is added to a long chain. It is passed
+// the bucket, the length of the chain, and the key just added.
+type ChainWarningFunc func(bucket, length int, key Hasher)
+
+// WithChainLengthWarning calls fn whenever an entry is added to a bucket whose
+// chain is then longer than threshold. Long chains mean lookups are degrading
+// towards a linear scan, which is usually caused by a poor Hash
+// implementation or by keys chosen to collide, such as in a hash flooding
+// attack. For backends other than Chaining, the chain is the sequence of slots
+// probed to find the entry, as in Stats. The GoMap backend never warns, as the
+// map's chains cannot be seen.
+//
+// fn is called while the dictionary is being changed, so it must not use the
+// dictionary.
+func WithChainLengthWarning(threshold int, fn ChainWarningFunc) OptionsFunc {
+	return func(d *Dictionary) {
+		d.chainThreshold = threshold
+		d.chainWarning = fn
+	}
+}
+
+// helper to check the chain an item was just added to.
+func (d *Dictionary) checkChain(i *item) {
+	if bucket, length := d.store.chain(i); length > d.chainThreshold {
+		d.chainWarning(bucket, length, i.key)
+	}
+}
@@ -0,0 +1,50 @@
+package dictionary
+
+import "fmt"
+
+// CheckInvariants verifies the internal consistency of the dictionary: that
+// the hash stored with each entry matches the hash of its key, that each entry
+// can be found where its hash says it should be, and that the counts kept by
+// the dictionary match its entries. It returns an error describing the first
+// problem found. It is meant for testing new backends and key types, and
+// does not modify the dictionary.
+func (d *Dictionary) CheckInvariants() error {
+	if d.store == nil {
+		return nil
+	}
+
+	var count, expiring, weight int
+	c := d.store.cursor()
+	for i := c.next(); i != nil; i = c.next() {
+		count++
+		if !i.expires.IsZero() {
+			expiring++
+		}
+		weight += i.weight
+
+		if h := d.hash(i.key); h != i.hash {
+			return fmt.Errorf("dictionary: key %v has hash %#x, but %#x is stored", i.key, h, i.hash)
+		}
+		if f := d.store.find(i.key, i.hash); f != i {
+			if f == nil {
+				return fmt.Errorf("dictionary: key %v cannot be found with its hash", i.key)
+			}
+			return fmt.Errorf("dictionary: key %v is present more than once", i.key)
+		}
+		if d.weigher != nil {
+			if w := d.weigher(i.key, i.value); w != i.weight {
+				return fmt.Errorf("dictionary: key %v has weight %d, but %d is stored", i.key, w, i.weight)
+			}
+		}
+	}
+
+	switch {
+	case count != d.count:
+		return fmt.Errorf("dictionary: found %d entries, but the count is %d", count, d.count)
+	case expiring != d.expiring:
+		return fmt.Errorf("dictionary: found %d expiring entries, but the count is %d", expiring, d.expiring)
+	case weight != d.weight:
+		return fmt.Errorf("dictionary: found entries weighing %d, but the total is %d", weight, d.weight)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Command dict is an interactive shell for exploring how a dictionary stores
+// its entries. Keys and values are strings. After each command that changes
+// the dictionary, the collision statistics and the layout of the buckets are
+// printed, so you can watch entries collide and move as the table changes.
+//
+// Type "help" for a list of commands.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bakins/dictionary"
+)
+
+var backends = map[string]dictionary.Backend{
+	"chaining": dictionary.Chaining,
+	"open":     dictionary.OpenAddressing,
+	"swiss":    dictionary.SwissTable,
+	"cuckoo":   dictionary.Cuckoo,
+	"map":      dictionary.GoMap,
+}
+
+const usage = `commands:
+  set KEY VALUE   set a key
+  get KEY         get a key
+  del KEY         delete a key
+  keys            list the keys
+  buckets N       move the entries into N buckets
+  backend NAME    start over with a backend: chaining, open, swiss, cuckoo or map
+  clear           remove every entry
+  stats           print the collision statistics
+  dump            print the bucket layout
+  quiet           toggle printing the statistics and layout after changes
+  help            print this message
+  quit            exit
+`
+
+// shell holds the state of an interactive session.
+type shell struct {
+	d       *dictionary.Dictionary
+	buckets uint32
+	backend dictionary.Backend
+	out     io.Writer
+	quiet   bool
+}
+
+func newShell(out io.Writer, buckets uint32, backend dictionary.Backend) *shell {
+	s := &shell{out: out, buckets: buckets}
+	s.backend = backend
+	s.reset()
+	return s
+}
+
+// reset replaces the dictionary with an empty one.
+func (s *shell) reset() {
+	s.d = dictionary.New(dictionary.SetBuckets(s.buckets), dictionary.WithBackend(s.backend))
+}
+
+// run reads commands from in until it is exhausted or "quit" is read.
+func (s *shell) run(in io.Reader, prompt bool) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		if prompt {
+			fmt.Fprint(s.out, "> ")
+		}
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "quit" || args[0] == "exit" {
+			return nil
+		}
+		changed, err := s.exec(args)
+		if err != nil {
+			fmt.Fprintf(s.out, "error: %v\n", err)
+			continue
+		}
+		if changed && !s.quiet {
+			s.stats()
+			s.d.Dump(s.out)
+		}
+	}
+}
+
+// exec runs a single command, and reports whether it changed the dictionary.
+func (s *shell) exec(args []string) (bool, error) {
+	cmd, args := args[0], args[1:]
+	want := map[string]int{"set": 2, "get": 1, "del": 1, "buckets": 1, "backend": 1}[cmd]
+	if len(args) != want {
+		return false, fmt.Errorf("%s takes %d arguments", cmd, want)
+	}
+
+	switch cmd {
+	case "set":
+		s.d.Set(dictionary.StringKey(args[0]), args[1])
+		return true, nil
+	case "get":
+		if v, ok := s.d.Get(dictionary.StringKey(args[0])); ok {
+			fmt.Fprintln(s.out, v)
+		} else {
+			fmt.Fprintln(s.out, "not found")
+		}
+	case "del":
+		if _, ok := s.d.Delete(dictionary.StringKey(args[0])); !ok {
+			fmt.Fprintln(s.out, "not found")
+			return false, nil
+		}
+		return true, nil
+	case "keys":
+		for _, k := range s.d.SortedKeys(nil) {
+			fmt.Fprintln(s.out, k)
+		}
+	case "buckets":
+		n, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil || n == 0 {
+			return false, fmt.Errorf("invalid number of buckets %q", args[0])
+		}
+		s.buckets = uint32(n)
+		s.d.Rehash(s.buckets)
+		return true, nil
+	case "backend":
+		b, ok := backends[args[0]]
+		if !ok {
+			return false, fmt.Errorf("unknown backend %q", args[0])
+		}
+		s.backend = b
+		s.reset()
+		return true, nil
+	case "clear":
+		s.reset()
+		return true, nil
+	case "stats":
+		s.stats()
+	case "dump":
+		return false, s.d.Dump(s.out)
+	case "quiet":
+		s.quiet = !s.quiet
+	case "help":
+		fmt.Fprint(s.out, usage)
+	default:
+		return false, fmt.Errorf("unknown command %q, try help", cmd)
+	}
+	return false, nil
+}
+
+func (s *shell) stats() {
+	st := s.d.Stats()
+	fmt.Fprintf(s.out, "entries: %d buckets: %d empty: %d load: %.2f longest chain: %d mean chain: %.2f\n",
+		st.Entries, st.Buckets, st.EmptyBuckets, st.LoadFactor, st.MaxChain, st.MeanChain)
+}
+
+func main() {
+	buckets := flag.Uint("buckets", 7, "initial number of buckets")
+	backend := flag.String("backend", "chaining", "backend: chaining, open, swiss or cuckoo")
+	flag.Parse()
+
+	b, ok := backends[*backend]
+	if !ok || *buckets == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	s := newShell(os.Stdout, uint32(*buckets), b)
+	if err := s.run(os.Stdin, true); err != nil {
+		fmt.Fprintf(os.Stderr, "dict: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bakins/dictionary"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShell(t *testing.T) {
+	var out bytes.Buffer
+	s := newShell(&out, 3, dictionary.Chaining)
+
+	in := strings.Join([]string{
+		"quiet",
+		"set a 1",
+		"set b 2",
+		"get a",
+		"del b",
+		"get b",
+		"bogus",
+		"set a",
+		"quit",
+		"get a",
+	}, "\n")
+	require.Nil(t, s.run(strings.NewReader(in), false))
+	require.Equal(t, "1\nnot found\nerror: unknown command \"bogus\", try help\nerror: set takes 2 arguments\n", out.String())
+
+	out.Reset()
+	require.Nil(t, s.run(strings.NewReader("quiet\nbuckets 5\n"), false))
+	require.True(t, strings.Contains(out.String(), "entries: 1 buckets: 5"), "statistics should be printed after changes")
+	require.True(t, strings.Contains(out.String(), "bucket 4:"), "layout should be printed after changes")
+}
@@ -0,0 +1,187 @@
+// Command dictgen generates a strongly typed wrapper around a
+// dictionary.Dictionary, for a given key and value type. It is meant to be
+// used with go generate:
+//
+//	//go:generate dictgen -type UserDict -key UserID -value *User
+//
+// The key type must implement dictionary.Hasher, or be one of string, []byte,
+// int, int64, uint64 or float64, which are converted to the matching key type
+// from the dictionary package.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// config is what the wrapper is generated from.
+type config struct {
+	Package string
+	Imports []string
+	Type    string
+	Key     string
+	Value   string
+}
+
+// keyConversion converts between a key type and a dictionary.Hasher. Each is a
+// format with a single %s for the expression to convert.
+type keyConversion struct {
+	wrap   string
+	unwrap string
+}
+
+// conversions for key types that do not implement dictionary.Hasher.
+var builtinKeys = map[string]keyConversion{
+	"string":  {"dictionary.StringKey(%s)", "string(%s.(dictionary.StringKey))"},
+	"[]byte":  {"dictionary.BytesKey(%s)", "[]byte(%s.(dictionary.BytesKey))"},
+	"int":     {"dictionary.Int64Key(%s)", "int(%s.(dictionary.Int64Key))"},
+	"int64":   {"dictionary.Int64Key(%s)", "int64(%s.(dictionary.Int64Key))"},
+	"uint64":  {"dictionary.Uint64Key(%s)", "uint64(%s.(dictionary.Uint64Key))"},
+	"float64": {"dictionary.Float64Key(%s)", "float64(%s.(dictionary.Float64Key))"},
+}
+
+const wrapperTemplate = `// Code generated by dictgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/bakins/dictionary"
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+
+// {{.Type}} is a dictionary from {{.Key}} to {{.Value}}.
+type {{.Type}} struct {
+	d *dictionary.Dictionary
+}
+
+// New{{.Type}} creates an empty {{.Type}}. Options are passed to
+// dictionary.New.
+func New{{.Type}}(options ...dictionary.OptionsFunc) *{{.Type}} {
+	return &{{.Type}}{d: dictionary.New(options...)}
+}
+
+// Dictionary returns the underlying dictionary.
+func (m *{{.Type}}) Dictionary() *dictionary.Dictionary {
+	return m.d
+}
+
+// Get returns the value for key. The second return value will be false if
+// not found.
+func (m *{{.Type}}) Get(key {{.Key}}) ({{.Value}}, bool) {
+	v, ok := m.d.Get({{wrap "key"}})
+	if !ok {
+		var zero {{.Value}}
+		return zero, false
+	}
+	return v.({{.Value}}), true
+}
+
+// Set sets the value for key.
+func (m *{{.Type}}) Set(key {{.Key}}, val {{.Value}}) {
+	m.d.Set({{wrap "key"}}, val)
+}
+
+// Delete removes key, and returns its value. The second return value will be
+// false if not found.
+func (m *{{.Type}}) Delete(key {{.Key}}) ({{.Value}}, bool) {
+	v, ok := m.d.Delete({{wrap "key"}})
+	if !ok {
+		var zero {{.Value}}
+		return zero, false
+	}
+	return v.({{.Value}}), true
+}
+
+// Contains reports whether key is present.
+func (m *{{.Type}}) Contains(key {{.Key}}) bool {
+	return m.d.Contains({{wrap "key"}})
+}
+
+// Len returns the number of entries.
+func (m *{{.Type}}) Len() int {
+	return m.d.Len()
+}
+
+// Each executes the function on each entry. Error returned will be any
+// error f returned to stop iteration.
+func (m *{{.Type}}) Each(f func({{.Key}}, {{.Value}}) error) error {
+	return m.d.Each(func(k dictionary.Hasher, v interface{}) error {
+		return f({{unwrap "k"}}, v.({{.Value}}))
+	})
+}
+
+// Keys returns all of the keys.
+func (m *{{.Type}}) Keys() []{{.Key}} {
+	keys := make([]{{.Key}}, 0, m.d.Len())
+	for _, k := range m.d.Keys() {
+		keys = append(keys, {{unwrap "k"}})
+	}
+	return keys
+}
+`
+
+// generate returns the formatted source of the wrapper.
+func generate(c config) ([]byte, error) {
+	conv, ok := builtinKeys[c.Key]
+	if !ok {
+		conv = keyConversion{"%s", "%s.(" + c.Key + ")"}
+	}
+
+	t, err := template.New("wrapper").Funcs(template.FuncMap{
+		"wrap":   func(s string) string { return fmt.Sprintf(conv.wrap, s) },
+		"unwrap": func(s string) string { return fmt.Sprintf(conv.unwrap, s) },
+	}).Parse(wrapperTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, c); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return src, nil
+}
+
+func main() {
+	c := config{}
+	flag.StringVar(&c.Type, "type", "", "name of the generated type")
+	flag.StringVar(&c.Key, "key", "", "key type")
+	flag.StringVar(&c.Value, "value", "", "value type")
+	flag.StringVar(&c.Package, "package", os.Getenv("GOPACKAGE"), "package of the generated file")
+	imports := flag.String("import", "", "comma separated import paths needed by the key and value types")
+	output := flag.String("output", "", "output file, by default the lowercased type with _dict.go")
+	flag.Parse()
+
+	if c.Type == "" || c.Key == "" || c.Value == "" || c.Package == "" {
+		fmt.Fprintln(os.Stderr, "dictgen: -type, -key, -value and -package are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *imports != "" {
+		c.Imports = strings.Split(*imports, ",")
+	}
+	if *output == "" {
+		*output = strings.ToLower(c.Type) + "_dict.go"
+	}
+
+	src, err := generate(c)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dictgen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "dictgen: %v\n", err)
+		os.Exit(1)
+	}
+}
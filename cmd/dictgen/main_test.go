@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := generate(config{
+		Package: "users",
+		Type:    "UserDict",
+		Key:     "string",
+		Value:   "*User",
+	})
+	require.Nil(t, err)
+	require.True(t, strings.Contains(string(src), "func (m *UserDict) Get(key string) (*User, bool) {"))
+	require.True(t, strings.Contains(string(src), "m.d.Get(dictionary.StringKey(key))"))
+	require.True(t, strings.Contains(string(src), "string(k.(dictionary.StringKey))"))
+
+	src, err = generate(config{
+		Package: "users",
+		Imports: []string{"time"},
+		Type:    "LoginDict",
+		Key:     "UserID",
+		Value:   "time.Time",
+	})
+	require.Nil(t, err)
+	require.True(t, strings.Contains(string(src), "\"time\""))
+	require.True(t, strings.Contains(string(src), "m.d.Get(key)"))
+	require.True(t, strings.Contains(string(src), "k.(UserID)"))
+
+	_, err = generate(config{Package: "users", Type: "Bad", Key: "string", Value: "*"})
+	require.NotNil(t, err, "invalid types should fail")
+}
@@ -0,0 +1,285 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bakins/dictionary"
+)
+
+// MarshalCBOR returns the CBOR encoding of d, a map. Map keys are sorted as
+// RFC 8949 requires for deterministic encoding, and only definite lengths are
+// used.
+func MarshalCBOR(d *dictionary.Dictionary) ([]byte, error) {
+	return marshal(cbor{}, d)
+}
+
+// UnmarshalCBOR decodes a CBOR map into d. Entries are added to d, replacing
+// any with the same keys. Indefinite length items are accepted, and tags are
+// ignored, so a tagged value decodes as the value itself.
+func UnmarshalCBOR(data []byte, d *dictionary.Dictionary) error {
+	dec := &cborDecoder{decoder{data: data}}
+	return unmarshal(&dec.decoder, d, dec.value)
+}
+
+// CBOR major types.
+const (
+	cborUint byte = iota
+	cborNegative
+	cborBytes
+	cborString
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const (
+	cborFalse = cborSimple<<5 | 20
+	cborTrue  = cborSimple<<5 | 21
+	cborNull  = cborSimple<<5 | 22
+	// the additional information for an indefinite length.
+	cborIndefinite = 31
+	cborBreak      = 0xff
+)
+
+type cbor struct{}
+
+// appendHead appends the initial byte for a major type, followed by the
+// argument in as few bytes as possible.
+func (cbor) appendHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return append(b, major|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return append(b, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	b = append(b, major|27)
+	for shift := 56; shift >= 0; shift -= 8 {
+		b = append(b, byte(n>>shift))
+	}
+	return b
+}
+
+func (cbor) appendNil(b []byte) []byte {
+	return append(b, cborNull)
+}
+
+func (cbor) appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, cborTrue)
+	}
+	return append(b, cborFalse)
+}
+
+func (c cbor) appendInt(b []byte, v int64) []byte {
+	if v < 0 {
+		// -1 - v, without overflowing.
+		return c.appendHead(b, cborNegative, ^uint64(v))
+	}
+	return c.appendHead(b, cborUint, uint64(v))
+}
+
+func (c cbor) appendUint(b []byte, v uint64) []byte {
+	return c.appendHead(b, cborUint, v)
+}
+
+// appendFloat uses single precision if it holds v exactly.
+func (cbor) appendFloat(b []byte, v float64) []byte {
+	if f := float32(v); float64(f) == v {
+		u := math.Float32bits(f)
+		return append(b, cborSimple<<5|26, byte(u>>24), byte(u>>16), byte(u>>8), byte(u))
+	}
+	u := math.Float64bits(v)
+	b = append(b, cborSimple<<5|27)
+	for shift := 56; shift >= 0; shift -= 8 {
+		b = append(b, byte(u>>shift))
+	}
+	return b
+}
+
+func (c cbor) appendString(b []byte, v string) []byte {
+	return append(c.appendHead(b, cborString, uint64(len(v))), v...)
+}
+
+func (c cbor) appendBytes(b []byte, v []byte) []byte {
+	return append(c.appendHead(b, cborBytes, uint64(len(v))), v...)
+}
+
+func (c cbor) appendArray(b []byte, n int) []byte {
+	return c.appendHead(b, cborArray, uint64(n))
+}
+
+func (c cbor) appendMap(b []byte, n int) []byte {
+	return c.appendHead(b, cborMap, uint64(n))
+}
+
+var errCBORIndefinite = errors.New("codec: invalid indefinite length CBOR item")
+
+type cborDecoder struct {
+	decoder
+}
+
+// head reads an initial byte, and returns its major type, additional
+// information and argument, and whether the length is indefinite.
+func (d *cborDecoder) head() (byte, byte, uint64, bool, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), false, nil
+	case info == cborIndefinite:
+		return major, info, 0, true, nil
+	case info > 27:
+		return 0, 0, 0, false, fmt.Errorf("codec: invalid CBOR initial byte 0x%02x", b[0])
+	}
+	b, err = d.next(1 << (info - 24))
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return major, info, n, false, nil
+}
+
+// end consumes the break that ends an indefinite length item, if it is next.
+func (d *cborDecoder) end() bool {
+	if len(d.data) > 0 && d.data[0] == cborBreak {
+		d.data = d.data[1:]
+		return true
+	}
+	return false
+}
+
+// length converts a count of items to the form decodeArray and decodeMap use.
+func length(n uint64, indefinite bool) (int64, error) {
+	switch {
+	case indefinite:
+		return -1, nil
+	case n > math.MaxInt64:
+		return 0, ErrTruncated
+	}
+	return int64(n), nil
+}
+
+func (d *cborDecoder) value() (interface{}, error) {
+	major, info, n, indefinite, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUint:
+		return integer(n), nil
+	case cborNegative:
+		if n > math.MaxInt64 {
+			return nil, errors.New("codec: CBOR negative integer overflows int64")
+		}
+		return -1 - int64(n), nil
+	case cborBytes, cborString:
+		var b []byte
+		if indefinite {
+			b, err = d.chunks(major)
+		} else {
+			b, err = d.next(n)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if major == cborString {
+			return string(b), nil
+		}
+		return append([]byte{}, b...), nil
+	case cborArray:
+		count, err := length(n, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(count, d.end, d.value)
+	case cborMap:
+		count, err := length(n, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(count, d.end, d.value)
+	case cborTag:
+		if indefinite {
+			return nil, errCBORIndefinite
+		}
+		// a chain of tags nests like an array.
+		done, err := d.enter()
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+		return d.value()
+	}
+
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		// null and undefined.
+		return nil, nil
+	case 25:
+		return halfFloat(uint16(n)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 27:
+		return math.Float64frombits(n), nil
+	}
+	return nil, fmt.Errorf("codec: unsupported CBOR simple value %d", n)
+}
+
+// chunks reads the definite length chunks of an indefinite length byte or
+// text string, and returns them joined.
+func (d *cborDecoder) chunks(major byte) ([]byte, error) {
+	var b []byte
+	for !d.end() {
+		m, _, n, indefinite, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || indefinite {
+			return nil, errCBORIndefinite
+		}
+		chunk, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+	return b, nil
+}
+
+// halfFloat converts an IEEE 754 half precision float.
+func halfFloat(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(frac+1024, exp-25)
+}
@@ -0,0 +1,330 @@
+// Package codec encodes dictionaries in compact binary formats, MessagePack
+// and CBOR, for exchanging them with services that do not use JSON or gob.
+//
+// Unlike JSON, both formats allow map keys other than strings, so StringKey,
+// Int64Key, Uint64Key, Float64Key and BytesKey keys are all supported and
+// decoded back to the same types, except that Uint64Key keys that fit in an
+// int64 are decoded as Int64Key. Other key types cannot be encoded.
+//
+// Values may be nil, booleans, numbers, strings, byte slices, and slices, maps
+// and pointers of these, as well as nested dictionaries. Decoded values are
+// nil, bool, int64, uint64 for integers too large for an int64, float64,
+// string, []byte, []interface{}, and *dictionary.Dictionary for maps.
+//
+// Encoded maps have their keys sorted by their encoded bytes, so the output is
+// stable, as CBOR's canonical form requires.
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/bakins/dictionary"
+)
+
+var (
+	// ErrTruncated is returned when decoding data that ends in the middle of
+	// a value.
+	ErrTruncated = errors.New("codec: unexpected end of data")
+	// ErrTooDeep is returned when decoding data with values nested more
+	// deeply than the decoder allows.
+	ErrTooDeep = errors.New("codec: values nested too deeply")
+)
+
+// the deepest nesting of arrays and maps decoded, so hostile input cannot
+// exhaust the stack.
+const maxDepth = 1000
+
+// format appends the encoding of a single value, or the header of an array or
+// map, to a buffer.
+type format interface {
+	appendNil(b []byte) []byte
+	appendBool(b []byte, v bool) []byte
+	appendInt(b []byte, v int64) []byte
+	appendUint(b []byte, v uint64) []byte
+	appendFloat(b []byte, v float64) []byte
+	appendString(b []byte, v string) []byte
+	appendBytes(b []byte, v []byte) []byte
+	appendArray(b []byte, n int) []byte
+	appendMap(b []byte, n int) []byte
+}
+
+var dictionaryType = reflect.TypeOf((*dictionary.Dictionary)(nil))
+
+// marshal encodes a dictionary using f.
+func marshal(f format, d *dictionary.Dictionary) ([]byte, error) {
+	return appendDictionary(f, nil, d)
+}
+
+// entry is a map entry with its key already encoded.
+type entry struct {
+	key   []byte
+	value interface{}
+}
+
+func appendDictionary(f format, b []byte, d *dictionary.Dictionary) ([]byte, error) {
+	entries := make([]entry, 0, d.Len())
+	err := d.Each(func(k dictionary.Hasher, v interface{}) error {
+		key, err := appendKey(f, nil, k)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: key, value: v})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	b = f.appendMap(b, len(entries))
+	for _, e := range entries {
+		b = append(b, e.key...)
+		if b, err = appendValue(f, b, reflect.ValueOf(e.value)); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func appendKey(f format, b []byte, k dictionary.Hasher) ([]byte, error) {
+	switch k := k.(type) {
+	case dictionary.StringKey:
+		return f.appendString(b, string(k)), nil
+	case dictionary.Int64Key:
+		return f.appendInt(b, int64(k)), nil
+	case dictionary.Uint64Key:
+		return f.appendUint(b, uint64(k)), nil
+	case dictionary.Float64Key:
+		return f.appendFloat(b, float64(k)), nil
+	case dictionary.BytesKey:
+		return f.appendBytes(b, k), nil
+	}
+	return nil, fmt.Errorf("codec: cannot encode key of type %T", k)
+}
+
+func appendValue(f format, b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return f.appendNil(b), nil
+	}
+	if v.Type() == dictionaryType {
+		if v.IsNil() {
+			return f.appendNil(b), nil
+		}
+		return appendDictionary(f, b, v.Interface().(*dictionary.Dictionary))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return f.appendBool(b, v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.appendInt(b, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return f.appendUint(b, v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return f.appendFloat(b, v.Float()), nil
+	case reflect.String:
+		return f.appendString(b, v.String()), nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return f.appendNil(b), nil
+		}
+		return appendValue(f, b, v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return f.appendNil(b), nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return f.appendBytes(b, v.Bytes()), nil
+		}
+		fallthrough
+	case reflect.Array:
+		b = f.appendArray(b, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			var err error
+			if b, err = appendValue(f, b, v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return f.appendNil(b), nil
+		}
+		return appendMap(f, b, v)
+	}
+	return nil, fmt.Errorf("codec: cannot encode value of type %s", v.Type())
+}
+
+// appendMap encodes a Go map, with its keys sorted like a dictionary's.
+func appendMap(f format, b []byte, v reflect.Value) ([]byte, error) {
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := appendValue(f, nil, iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key: key, value: iter.Value().Interface()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	b = f.appendMap(b, len(entries))
+	for _, e := range entries {
+		b = append(b, e.key...)
+		var err error
+		if b, err = appendValue(f, b, reflect.ValueOf(e.value)); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// toKey converts a decoded map key to a dictionary key.
+func toKey(v interface{}) (dictionary.Hasher, error) {
+	switch v := v.(type) {
+	case string:
+		return dictionary.StringKey(v), nil
+	case int64:
+		return dictionary.Int64Key(v), nil
+	case uint64:
+		return dictionary.Uint64Key(v), nil
+	case float64:
+		return dictionary.Float64Key(v), nil
+	case []byte:
+		return dictionary.BytesKey(v), nil
+	}
+	return nil, fmt.Errorf("codec: cannot decode key of type %T", v)
+}
+
+// integer returns an unsigned integer as an int64 if it fits.
+func integer(u uint64) interface{} {
+	if u <= math.MaxInt64 {
+		return int64(u)
+	}
+	return u
+}
+
+// decoder holds the data left to decode. Each format decodes its values using
+// these helpers.
+type decoder struct {
+	data  []byte
+	depth int
+}
+
+// next returns the next n bytes.
+func (d *decoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)) {
+		return nil, ErrTruncated
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+func (d *decoder) string(n uint64) (interface{}, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// bytes returns a copy, so the result does not refer to the data being
+// decoded.
+func (d *decoder) bytes(n uint64) (interface{}, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, b...), nil
+}
+
+// enter is called when decoding an array or map, and returns a function to
+// call when done.
+func (d *decoder) enter() (func(), error) {
+	d.depth++
+	if d.depth > maxDepth {
+		return nil, ErrTooDeep
+	}
+	return func() { d.depth-- }, nil
+}
+
+// decodeArray decodes n values, or if n is negative, values until end returns
+// true.
+func (d *decoder) decodeArray(n int64, end func() bool, value func() (interface{}, error)) (interface{}, error) {
+	done, err := d.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if n > int64(len(d.data)) {
+		return nil, ErrTruncated
+	}
+	a := make([]interface{}, 0, max(n, 0))
+	for ; n != 0 && (n > 0 || !end()); n-- {
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	return a, nil
+}
+
+// decodeMap decodes n pairs of keys and values into a new dictionary, or if n
+// is negative, pairs until end returns true.
+func (d *decoder) decodeMap(n int64, end func() bool, value func() (interface{}, error)) (interface{}, error) {
+	done, err := d.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if n > int64(len(d.data)) {
+		return nil, ErrTruncated
+	}
+	m := dictionary.New()
+	for ; n != 0 && (n > 0 || !end()); n-- {
+		k, err := value()
+		if err != nil {
+			return nil, err
+		}
+		key, err := toKey(k)
+		if err != nil {
+			return nil, err
+		}
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		m.Set(key, v)
+	}
+	return m, nil
+}
+
+// unmarshal decodes a map into dst, using value to decode a format's values.
+func unmarshal(d *decoder, dst *dictionary.Dictionary, value func() (interface{}, error)) error {
+	v, err := value()
+	if err != nil {
+		return err
+	}
+	if len(d.data) != 0 {
+		return errors.New("codec: data after the end of the map")
+	}
+	src, ok := v.(*dictionary.Dictionary)
+	if !ok {
+		return fmt.Errorf("codec: cannot decode %T into a dictionary", v)
+	}
+	return src.Each(func(k dictionary.Hasher, v interface{}) error {
+		dst.Set(k, v)
+		return nil
+	})
+}
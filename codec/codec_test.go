@@ -0,0 +1,82 @@
+package codec_test
+
+import (
+	"testing"
+
+	"github.com/bakins/dictionary"
+	"github.com/bakins/dictionary/codec"
+	"github.com/stretchr/testify/require"
+)
+
+func testDictionary() *dictionary.Dictionary {
+	d := dictionary.New()
+	d.Set(dictionary.StringKey("s"), "x")
+	d.Set(dictionary.Int64Key(-300), int64(-1))
+	d.Set(dictionary.Uint64Key(1<<63), uint64(1<<63))
+	d.Set(dictionary.Float64Key(1.5), 0.1)
+	d.Set(dictionary.BytesKey("b"), []byte{1, 2})
+	d.Set(dictionary.StringKey("list"), []interface{}{true, nil, "y"})
+	return d
+}
+
+func testRoundTrip(t *testing.T, marshal func(*dictionary.Dictionary) ([]byte, error), unmarshal func([]byte, *dictionary.Dictionary) error) {
+	d := testDictionary()
+	nested := dictionary.New()
+	nested.Set(dictionary.StringKey("n"), int64(1))
+	d.Set(dictionary.StringKey("nested"), nested)
+
+	data, err := marshal(d)
+	require.Nil(t, err)
+	again, err := marshal(d)
+	require.Nil(t, err)
+	require.Equal(t, data, again, "encoding should be stable")
+
+	out := dictionary.New()
+	require.Nil(t, unmarshal(data, out))
+	v, ok := out.Get(dictionary.StringKey("nested"))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, true, nested.Equal(v.(*dictionary.Dictionary), nil), "nested dictionaries should be equal")
+
+	out.Delete(dictionary.StringKey("nested"))
+	d.Delete(dictionary.StringKey("nested"))
+	require.Equal(t, true, d.Equal(out, nil), "dictionaries should be equal")
+
+	bad := dictionary.New()
+	bad.Set(dictionary.CompositeKey{dictionary.StringKey("a")}, 1)
+	_, err = marshal(bad)
+	require.NotNil(t, err)
+
+	require.Equal(t, codec.ErrTruncated, unmarshal(data[:len(data)-1], dictionary.New()))
+	require.NotNil(t, unmarshal(append(data, 0), dictionary.New()))
+}
+
+func TestMsgPack(t *testing.T) {
+	d := dictionary.New()
+	d.Set(dictionary.StringKey("b"), 300)
+	d.Set(dictionary.StringKey("a"), []interface{}{-1, "x"})
+	data, err := codec.MarshalMsgPack(d)
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x82, 0xa1, 'a', 0x92, 0xff, 0xa1, 'x', 0xa1, 'b', 0xcd, 0x01, 0x2c}, data)
+
+	testRoundTrip(t, codec.MarshalMsgPack, codec.UnmarshalMsgPack)
+}
+
+func TestCBOR(t *testing.T) {
+	d := dictionary.New()
+	d.Set(dictionary.StringKey("b"), 300)
+	d.Set(dictionary.StringKey("a"), []interface{}{-1, 1.5})
+	data, err := codec.MarshalCBOR(d)
+	require.Nil(t, err)
+	require.Equal(t, []byte{0xa2, 0x61, 'a', 0x82, 0x20, 0xfa, 0x3f, 0xc0, 0x00, 0x00, 0x61, 'b', 0x19, 0x01, 0x2c}, data)
+
+	testRoundTrip(t, codec.MarshalCBOR, codec.UnmarshalCBOR)
+
+	// an indefinite length map holding a tagged, indefinite length string
+	// and a half precision float.
+	out := dictionary.New()
+	require.Nil(t, codec.UnmarshalCBOR([]byte{0xbf, 0x61, 'k', 0xc0, 0x7f, 0x61, 'a', 0x61, 'b', 0xff, 0x61, 'f', 0xf9, 0x3e, 0x00, 0xff}, out))
+	v, _ := out.Get(dictionary.StringKey("k"))
+	require.Equal(t, "ab", v)
+	v, _ = out.Get(dictionary.StringKey("f"))
+	require.Equal(t, 1.5, v)
+}
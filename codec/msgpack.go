@@ -0,0 +1,186 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/bakins/dictionary"
+)
+
+// MarshalMsgPack returns the MessagePack encoding of d, a map.
+func MarshalMsgPack(d *dictionary.Dictionary) ([]byte, error) {
+	return marshal(msgpack{}, d)
+}
+
+// UnmarshalMsgPack decodes a MessagePack map into d. Entries are added to d,
+// replacing any with the same keys.
+func UnmarshalMsgPack(data []byte, d *dictionary.Dictionary) error {
+	dec := &msgpackDecoder{decoder{data: data}}
+	return unmarshal(&dec.decoder, d, dec.value)
+}
+
+// msgpack encodes each value in its smallest form.
+type msgpack struct{}
+
+func (msgpack) appendNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+func (msgpack) appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+func (m msgpack) appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return m.appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+}
+
+func (msgpack) appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= 0x7f:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xcf), v)
+}
+
+func (msgpack) appendFloat(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+// appendLength appends the header for a string, binary, array or map, using
+// the fixed form if there is one and n fits.
+func (msgpack) appendLength(b []byte, n int, fixed byte, fixedMax int, op8, op16, op32 byte) []byte {
+	switch {
+	case fixedMax > 0 && n <= fixedMax:
+		return append(b, fixed|byte(n))
+	case op8 != 0 && n <= math.MaxUint8:
+		return append(b, op8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, op16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, op32), uint32(n))
+}
+
+func (m msgpack) appendString(b []byte, v string) []byte {
+	b = m.appendLength(b, len(v), 0xa0, 31, 0xd9, 0xda, 0xdb)
+	return append(b, v...)
+}
+
+func (m msgpack) appendBytes(b []byte, v []byte) []byte {
+	b = m.appendLength(b, len(v), 0, 0, 0xc4, 0xc5, 0xc6)
+	return append(b, v...)
+}
+
+func (m msgpack) appendArray(b []byte, n int) []byte {
+	return m.appendLength(b, n, 0x90, 15, 0, 0xdc, 0xdd)
+}
+
+func (m msgpack) appendMap(b []byte, n int) []byte {
+	return m.appendLength(b, n, 0x80, 15, 0, 0xde, 0xdf)
+}
+
+type msgpackDecoder struct {
+	decoder
+}
+
+// uint reads a big endian unsigned integer of n bytes.
+func (d *msgpackDecoder) uint(n uint64) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (d *msgpackDecoder) value() (interface{}, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	switch op := b[0]; {
+	case op <= 0x7f:
+		return int64(op), nil
+	case op >= 0xe0:
+		return int64(int8(op)), nil
+	case op&0xf0 == 0x80:
+		return d.decodeMap(int64(op&0x0f), nil, d.value)
+	case op&0xf0 == 0x90:
+		return d.decodeArray(int64(op&0x0f), nil, d.value)
+	case op&0xe0 == 0xa0:
+		return d.string(uint64(op & 0x1f))
+	}
+
+	switch op := b[0]; op {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (op - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.bytes(n)
+	case 0xca:
+		u, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (op - 0xcc))
+		return integer(u), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := uint64(1) << (op - 0xd0)
+		u, err := d.uint(size)
+		// sign extend from the top bit of the value.
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (op - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.string(n)
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (op - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int64(n), nil, d.value)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (op - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int64(n), nil, d.value)
+	}
+	return nil, fmt.Errorf("codec: unsupported MessagePack type 0x%02x", b[0])
+}
@@ -0,0 +1,294 @@
+package dictionary_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bakins/dictionary"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiDict(t *testing.T) {
+	m := dictionary.NewMultiDict()
+	k := dictionary.StringKey("accept")
+
+	require.Nil(t, m.Get(k))
+
+	m.Add(k, "text/html")
+	m.Add(k, "text/plain")
+	m.Add(k, "text/html")
+	m.Add(dictionary.StringKey("host"), "example.com")
+
+	require.Equal(t, 2, m.Len(), "unexpected length")
+	require.Equal(t, []interface{}{"text/html", "text/plain", "text/html"}, m.Get(k))
+
+	require.Equal(t, true, m.DeleteValue(k, "text/html"), "should have removed value")
+	require.Equal(t, []interface{}{"text/plain", "text/html"}, m.Get(k))
+	require.Equal(t, false, m.DeleteValue(k, "image/png"), "should not have removed value")
+
+	m.DeleteValue(k, "text/plain")
+	m.DeleteValue(k, "text/html")
+	require.Nil(t, m.Get(k))
+	require.Equal(t, 1, m.Len(), "unexpected length")
+
+	m.Delete(dictionary.StringKey("host"))
+	require.Equal(t, 0, len(m.Keys()), "unexpected number of keys")
+}
+
+func TestGroupBy(t *testing.T) {
+	words := []string{"apple", "bob", "avocado", "cherry", "banana"}
+	first := func(w string) dictionary.Hasher { return dictionary.StringKey(w[:1]) }
+
+	m := dictionary.GroupBy(words, first, nil)
+	require.Equal(t, 3, m.Len(), "unexpected length")
+	require.Equal(t, []interface{}{"apple", "avocado"}, m.Get(dictionary.StringKey("a")))
+	require.Equal(t, []interface{}{"bob", "banana"}, m.Get(dictionary.StringKey("b")))
+
+	m = dictionary.GroupBy(words, first, func(w string) interface{} { return len(w) })
+	require.Equal(t, []interface{}{6}, m.Get(dictionary.StringKey("c")))
+}
+
+func TestPersistent(t *testing.T) {
+	empty := dictionary.NewPersistent()
+	p := empty
+	for n := 0; n < 1000; n++ {
+		p = p.Set(dictionary.Int64Key(n), n)
+	}
+	require.Equal(t, 1000, p.Len(), "unexpected length")
+	require.Equal(t, 0, empty.Len(), "old version should not change")
+
+	q := p.Set(dictionary.Int64Key(1), "one").Delete(dictionary.Int64Key(2))
+	require.Equal(t, 999, q.Len(), "unexpected length")
+	v, _ := q.Get(dictionary.Int64Key(1))
+	require.Equal(t, "one", v, "unexpected value")
+	require.Equal(t, false, q.Contains(dictionary.Int64Key(2)), "key should have been deleted")
+
+	for n := 0; n < 1000; n++ {
+		v, ok := p.Get(dictionary.Int64Key(n))
+		require.Equal(t, true, ok, "old version should not change")
+		require.Equal(t, n, v.(int), "old version should not change")
+	}
+	require.Equal(t, p, p.Delete(dictionary.Int64Key(1000)), "deleting a missing key should not copy")
+
+	for n := 0; n < 1000; n++ {
+		p = p.Delete(dictionary.Int64Key(n))
+	}
+	require.Equal(t, 0, p.Len(), "unexpected length")
+	require.Len(t, q.Keys(), 999)
+
+	// keys with the same hash.
+	p = empty
+	for n := 0; n < 10; n++ {
+		p = p.Set(sameKey(n), n)
+	}
+	for n := 0; n < 10; n += 2 {
+		p = p.Delete(sameKey(n))
+	}
+	require.Equal(t, 5, p.Len(), "unexpected length")
+	for n := 0; n < 10; n++ {
+		require.Equal(t, n%2 == 1, p.Contains(sameKey(n)), "unexpected key")
+	}
+}
+
+func TestVersioned(t *testing.T) {
+	v := dictionary.NewVersioned()
+	require.Equal(t, uint64(0), v.Version(), "unexpected version")
+
+	require.Equal(t, uint64(1), v.Set(dictionary.StringKey("a"), 1), "unexpected version")
+	require.Equal(t, uint64(2), v.Set(dictionary.StringKey("a"), 2), "unexpected version")
+	require.Equal(t, uint64(3), v.Set(dictionary.StringKey("b"), 3), "unexpected version")
+	require.Equal(t, uint64(4), v.Delete(dictionary.StringKey("a")), "unexpected version")
+	require.Equal(t, uint64(4), v.Delete(dictionary.StringKey("a")), "deleting a missing key should not create a version")
+
+	p, err := v.At(1)
+	require.Nil(t, err)
+	val, _ := p.Get(dictionary.StringKey("a"))
+	require.Equal(t, 1, val.(int), "unexpected value")
+	require.Equal(t, false, p.Contains(dictionary.StringKey("b")), "key should not be present")
+
+	_, err = v.At(5)
+	require.Equal(t, dictionary.ErrUnknownVersion, err)
+
+	version, err := v.Rollback(2)
+	require.Nil(t, err)
+	require.Equal(t, uint64(5), version, "unexpected version")
+	val, _ = v.Get(dictionary.StringKey("a"))
+	require.Equal(t, 2, val.(int), "unexpected value")
+	require.Equal(t, 1, v.Len(), "unexpected length")
+
+	p, _ = v.At(4)
+	require.Equal(t, 1, p.Len(), "history should be kept")
+}
+
+func TestFunctional(t *testing.T) {
+	d := dictionary.New()
+	for n := 0; n < 10; n++ {
+		d.Set(dictionary.Int64Key(n), n)
+	}
+
+	even := d.Filter(func(_ dictionary.Hasher, v interface{}) bool {
+		return v.(int)%2 == 0
+	})
+	require.Equal(t, 5, even.Len(), "unexpected length")
+	require.Equal(t, false, even.Contains(dictionary.Int64Key(1)), "key should have been filtered")
+
+	squares := d.MapValues(func(_ dictionary.Hasher, v interface{}) interface{} {
+		return v.(int) * v.(int)
+	})
+	require.Equal(t, 10, squares.Len(), "unexpected length")
+	v, _ := squares.Get(dictionary.Int64Key(3))
+	require.Equal(t, 9, v.(int), "unexpected value")
+	v, _ = d.Get(dictionary.Int64Key(3))
+	require.Equal(t, 3, v.(int), "original should not change")
+
+	sum := d.Reduce(0, func(acc interface{}, _ dictionary.Hasher, v interface{}) interface{} {
+		return acc.(int) + v.(int)
+	})
+	require.Equal(t, 45, sum.(int), "unexpected sum")
+}
+
+func TestFind(t *testing.T) {
+	d := dictionary.New()
+	for n := 0; n < 10; n++ {
+		d.Set(dictionary.Int64Key(n), n)
+	}
+
+	calls := 0
+	k, v, ok := d.Find(func(_ dictionary.Hasher, v interface{}) bool {
+		calls++
+		return v.(int) >= 0
+	})
+	require.Equal(t, true, ok, "should have found an entry")
+	require.Equal(t, int(k.(dictionary.Int64Key)), v.(int), "unexpected value")
+	require.Equal(t, 1, calls, "should stop at the first match")
+
+	_, _, ok = d.Find(func(_ dictionary.Hasher, v interface{}) bool { return v.(int) > 10 })
+	require.Equal(t, false, ok, "should not have found an entry")
+
+	keys := d.KeysWhere(func(_ dictionary.Hasher, v interface{}) bool { return v.(int) > 6 })
+	require.ElementsMatch(t, []dictionary.Hasher{
+		dictionary.Int64Key(7), dictionary.Int64Key(8), dictionary.Int64Key(9),
+	}, keys)
+}
+
+func TestReadMostly(t *testing.T) {
+	r := dictionary.NewReadMostly()
+	for n := 0; n < 100; n++ {
+		r.Set(dictionary.Int64Key(n), n)
+	}
+	before := r.Snapshot()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				k := dictionary.Int64Key(n % 100)
+				if v, ok := r.Get(k); ok && v.(int) != n%100 {
+					t.Errorf("unexpected value %v for %v", v, k)
+					return
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 100; n += 2 {
+			r.Delete(dictionary.Int64Key(n))
+		}
+	}()
+	wg.Wait()
+
+	require.Equal(t, 50, r.Len(), "unexpected length")
+	require.Equal(t, 100, before.Len(), "snapshot should not change")
+	require.Equal(t, false, r.Delete(dictionary.Int64Key(0)), "key should already be deleted")
+
+	r.Update(func(p *dictionary.Persistent) *dictionary.Persistent {
+		return p.Set(dictionary.Int64Key(0), 0).Set(dictionary.Int64Key(2), 2)
+	})
+	require.Equal(t, 52, r.Len(), "unexpected length")
+}
+
+func TestLoading(t *testing.T) {
+	var loads int
+	fail := errors.New("not found")
+	l := dictionary.NewLoading(func(key dictionary.Hasher) (interface{}, error) {
+		loads++
+		if key == dictionary.StringKey("missing") {
+			return nil, fail
+		}
+		return strings.ToUpper(string(key.(dictionary.StringKey))), nil
+	})
+
+	v, err := l.Get(dictionary.StringKey("a"))
+	require.Nil(t, err)
+	require.Equal(t, "A", v)
+	v, err = l.Get(dictionary.StringKey("a"))
+	require.Nil(t, err)
+	require.Equal(t, "A", v)
+	require.Equal(t, 1, loads, "should only load once")
+
+	_, err = l.Get(dictionary.StringKey("missing"))
+	require.Equal(t, fail, err)
+	require.Equal(t, false, l.Contains(dictionary.StringKey("missing")), "failed load should not be stored")
+
+	l.Delete(dictionary.StringKey("a"))
+	_, err = l.Get(dictionary.StringKey("a"))
+	require.Nil(t, err)
+	require.Equal(t, 3, loads, "should load again after delete")
+	require.Equal(t, 1, l.Len(), "unexpected length")
+}
+
+func TestLoadingSingleflight(t *testing.T) {
+	var loads int32
+	release := make(chan struct{})
+	l := dictionary.NewLoading(func(key dictionary.Hasher) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return 1, nil
+	})
+
+	var wg sync.WaitGroup
+	for w := 0; w < 10; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := l.Get(dictionary.StringKey("a"))
+			if err != nil || v != 1 {
+				t.Errorf("unexpected result %v, %v", v, err)
+			}
+		}()
+	}
+	// let the callers pile up behind the first load.
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&loads), "should only load once")
+
+	// a value set while loading is not replaced by the load.
+	block := make(chan struct{})
+	started := make(chan struct{})
+	l = dictionary.NewLoading(func(key dictionary.Hasher) (interface{}, error) {
+		close(started)
+		<-block
+		return "loaded", nil
+	})
+	done := make(chan interface{})
+	go func() {
+		v, _ := l.Get(dictionary.StringKey("b"))
+		done <- v
+	}()
+	<-started
+	l.Set(dictionary.StringKey("b"), "set")
+	close(block)
+	require.Equal(t, "loaded", <-done)
+	v, err := l.Get(dictionary.StringKey("b"))
+	require.Nil(t, err)
+	require.Equal(t, "set", v)
+}
@@ -0,0 +1,62 @@
+package dictionary
+
+import "hash/maphash"
+
+// CompositeKey combines several keys into one, such as for keying on a
+// (tenant, name) pair. Keys are equal if each of their parts are equal.
+type CompositeKey []Hasher
+
+// NewCompositeKey creates a CompositeKey from its parts.
+func NewCompositeKey(parts ...Hasher) CompositeKey {
+	return CompositeKey(parts)
+}
+
+// combine mixes the hash of the next part into h. The order of the parts
+// matters, so (a, b) and (b, a) hash differently.
+func combine(h, part uint64) uint64 {
+	return mix(h + part + 0x9e3779b97f4a7c15)
+}
+
+// Hash generates a hash from the hashes of the parts.
+func (c CompositeKey) Hash() uint32 {
+	return fold(c.Hash64())
+}
+
+// Hash64 generates a hash from the 64 bit hashes of the parts, where they
+// have them.
+func (c CompositeKey) Hash64() uint64 {
+	var h uint64
+	for _, p := range c {
+		if k, ok := p.(Hasher64); ok {
+			h = combine(h, k.Hash64())
+		} else {
+			h = combine(h, uint64(p.Hash()))
+		}
+	}
+	return h
+}
+
+// HashSeed generates a hash from the hashes of the parts, using seed for parts
+// that implement SeededHasher.
+func (c CompositeKey) HashSeed(seed maphash.Seed) uint64 {
+	var h uint64
+	for _, p := range c {
+		h = combine(h, hashKey(p, seed))
+	}
+	return h
+}
+
+// Equal reports whether v, which must also be a CompositeKey, has the same
+// number of parts and each of them are equal.
+func (c CompositeKey) Equal(v interface{}) bool {
+	o := v.(CompositeKey)
+	if len(c) != len(o) {
+		return false
+	}
+	for n, p := range c {
+		if !p.Equal(o[n]) {
+			return false
+		}
+	}
+	return true
+}
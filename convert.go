@@ -0,0 +1,47 @@
+package dictionary
+
+import "fmt"
+
+// FromMap creates a new dictionary containing the entries of m. Options are
+// passed to New.
+func FromMap(m map[Hasher]interface{}, options ...OptionsFunc) *Dictionary {
+	d := New(options...)
+	for k, v := range m {
+		d.Set(k, v)
+	}
+	return d
+}
+
+// FromStringMap creates a new dictionary containing the entries of m, using
+// StringKey for the keys. Options are passed to New.
+func FromStringMap(m map[string]interface{}, options ...OptionsFunc) *Dictionary {
+	d := New(options...)
+	for k, v := range m {
+		d.Set(StringKey(k), v)
+	}
+	return d
+}
+
+// ToMap returns the entries of the dictionary as a map. It will panic if any
+// of the keys are not comparable, as they cannot be used as map keys.
+func (d *Dictionary) ToMap() map[Hasher]interface{} {
+	m := make(map[Hasher]interface{}, d.Len())
+	d.walk(func(i *item) bool {
+		m[i.key] = i.value
+		return true
+	})
+	return m
+}
+
+// ToStringMap returns the entries of the dictionary as a map keyed by string.
+// Keys are converted using fmt.Sprint, so keys implementing fmt.Stringer use
+// their String method. If several keys convert to the same string, only one of
+// the entries is kept.
+func (d *Dictionary) ToStringMap() map[string]interface{} {
+	m := make(map[string]interface{}, d.Len())
+	d.walk(func(i *item) bool {
+		m[fmt.Sprint(i.key)] = i.value
+		return true
+	})
+	return m
+}
@@ -0,0 +1,78 @@
+package dictionary
+
+import "sort"
+
+// Counter tallies how many times keys have been seen. It is built on a
+// Dictionary with int values.
+type Counter struct {
+	d *Dictionary
+}
+
+// Tally is a key and its count, as returned by MostCommon.
+type Tally struct {
+	Key   Hasher
+	Count int
+}
+
+// NewCounter creates an empty counter. Options are passed to New for the
+// underlying dictionary.
+func NewCounter(options ...OptionsFunc) *Counter {
+	return &Counter{d: New(options...)}
+}
+
+// Incr adds one to the count for key and returns the new count.
+func (c *Counter) Incr(key Hasher) int {
+	return c.Add(key, 1)
+}
+
+// Add adds n, which may be negative, to the count for key and returns the new
+// count. If the counter is bounded and turns a new key away, as with
+// WithTinyLFU, the count stays at zero.
+func (c *Counter) Add(key Hasher, n int) int {
+	v, ok := c.d.Update(key, func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return n, false
+		}
+		return old.(int) + n, false
+	})
+	if !ok {
+		return 0
+	}
+	return v.(int)
+}
+
+// Count returns the count for key, which is zero if it has not been seen.
+func (c *Counter) Count(key Hasher) int {
+	if v, ok := c.d.Get(key); ok {
+		return v.(int)
+	}
+	return 0
+}
+
+// Len returns the number of keys that have been counted.
+func (c *Counter) Len() int {
+	return c.d.Len()
+}
+
+type tallySorter []Tally
+
+func (s tallySorter) Len() int           { return len(s) }
+func (s tallySorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s tallySorter) Less(i, j int) bool { return s[i].Count > s[j].Count }
+
+// MostCommon returns the n keys with the highest counts, from most to least
+// common. If n is less than one, all of the keys are returned. The order of
+// keys with equal counts is unspecified.
+func (c *Counter) MostCommon(n int) []Tally {
+	tallies := make([]Tally, 0, c.d.Len())
+	c.d.walk(func(i *item) bool {
+		tallies = append(tallies, Tally{Key: i.key, Count: i.value.(int)})
+		return true
+	})
+	sort.Sort(tallySorter(tallies))
+
+	if n > 0 && n < len(tallies) {
+		tallies = tallies[:n]
+	}
+	return tallies
+}
@@ -0,0 +1,30 @@
+package dictionary_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bakins/dictionary"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounter(t *testing.T) {
+	c := dictionary.NewCounter()
+
+	for _, w := range strings.Fields("the cat and the dog and the bird") {
+		c.Incr(dictionary.StringKey(w))
+	}
+
+	require.Equal(t, 5, c.Len(), "unexpected length")
+	require.Equal(t, 3, c.Count(dictionary.StringKey("the")), "unexpected count")
+	require.Equal(t, 0, c.Count(dictionary.StringKey("fish")), "unexpected count")
+	require.Equal(t, 1, c.Add(dictionary.StringKey("the"), -2), "unexpected count")
+
+	c.Add(dictionary.StringKey("cat"), 10)
+
+	require.Equal(t, []dictionary.Tally{
+		{Key: dictionary.StringKey("cat"), Count: 11},
+		{Key: dictionary.StringKey("and"), Count: 2},
+	}, c.MostCommon(2))
+	require.Equal(t, 5, len(c.MostCommon(0)), "unexpected length")
+}
@@ -0,0 +1,229 @@
+package dictionary
+
+// cuckooTable is a cuckoo hashing store. Every item has exactly one slot in
+// each of two tables, chosen by two different hashes, so a lookup never needs
+// more than two probes. Inserting into an occupied slot kicks the occupant out
+// to its slot in the other table, which may kick out another item, and so on.
+// If this goes on too long, the tables are grown and rehashed. Items that
+// cannot be placed at all, such as when more than two keys have the same hash,
+// are kept in a small stash that is searched after the tables.
+type cuckooTable struct {
+	tables [2][]*item
+	stash  []*item
+	count  int
+}
+
+// the most items moved by a single insert before giving up.
+const maxCuckooKicks = 32
+
+func newCuckooTable(n uint32) *cuckooTable {
+	size := minOpenSlots
+	for size < int(n) {
+		size <<= 1
+	}
+	t := &cuckooTable{}
+	t.tables[0] = make([]*item, size)
+	t.tables[1] = make([]*item, size)
+	return t
+}
+
+// pos returns the slot for hash h in table n.
+func (t *cuckooTable) pos(n int, h uint64) int {
+	mask := uint64(len(t.tables[n]) - 1)
+	if n == 0 {
+		return int(mix(h) & mask)
+	}
+	// a different constant, so the two hashes are independent.
+	return int(mix(h^0x9e3779b97f4a7c15) & mask)
+}
+
+func (t *cuckooTable) find(key Hasher, h uint64) *item {
+	for n := range t.tables {
+		if i := t.tables[n][t.pos(n, h)]; i != nil && i.hash == h && key.Equal(i.key) {
+			return i
+		}
+	}
+	for _, i := range t.stash {
+		if i.hash == h && key.Equal(i.key) {
+			return i
+		}
+	}
+	return nil
+}
+
+func (t *cuckooTable) insert(i *item) {
+	// cuckoo hashing needs plenty of free slots to place items quickly, so
+	// keep the tables at most half full.
+	if t.count+1 > len(t.tables[0]) {
+		t.resize()
+	}
+	t.count++
+	if i = t.place(i); i == nil {
+		return
+	}
+	// the kicks went on too long. If the tables are less than a quarter
+	// full, this is most likely due to colliding hashes, which a larger table
+	// may not fix.
+	if t.count*2 < len(t.tables[0]) {
+		t.stash = append(t.stash, i)
+		return
+	}
+	t.resize()
+	if i = t.place(i); i != nil {
+		t.stash = append(t.stash, i)
+	}
+}
+
+// place puts an item into the tables, kicking out other items as needed. It
+// returns the item left without a slot, if it gives up.
+func (t *cuckooTable) place(i *item) *item {
+	for n := range t.tables {
+		p := t.pos(n, i.hash)
+		if t.tables[n][p] == nil {
+			t.tables[n][p] = i
+			return nil
+		}
+	}
+
+	n := 0
+	for kicks := 0; kicks < maxCuckooKicks; kicks++ {
+		p := t.pos(n, i.hash)
+		i, t.tables[n][p] = t.tables[n][p], i
+		if i == nil {
+			return nil
+		}
+		// the kicked out item goes to its slot in the other table.
+		n = 1 - n
+	}
+	return i
+}
+
+func (t *cuckooTable) delete(i *item) bool {
+	for n := range t.tables {
+		if p := t.pos(n, i.hash); t.tables[n][p] == i {
+			t.tables[n][p] = nil
+			t.count--
+			return true
+		}
+	}
+	for s, v := range t.stash {
+		if v == i {
+			t.stash = append(t.stash[:s], t.stash[s+1:]...)
+			t.count--
+			return true
+		}
+	}
+	return false
+}
+
+// resize doubles the tables and places the items again. Items that still
+// cannot be placed are stashed rather than growing again.
+func (t *cuckooTable) resize() {
+	old := t.tables
+	stash := t.stash
+	size := len(old[0]) << 1
+	t.tables[0] = make([]*item, size)
+	t.tables[1] = make([]*item, size)
+	t.stash = nil
+	place := func(i *item) {
+		if i != nil {
+			if i = t.place(i); i != nil {
+				t.stash = append(t.stash, i)
+			}
+		}
+	}
+	for n := range old {
+		for _, i := range old[n] {
+			place(i)
+		}
+	}
+	for _, i := range stash {
+		place(i)
+	}
+}
+
+func (t *cuckooTable) capacity() int {
+	return len(t.tables[0])
+}
+
+// items in the first table take one probe, and those in the second take two.
+// Items in the stash take two, and then one more for each stashed item
+// before them.
+func (t *cuckooTable) stats(s *Stats) {
+	for n := range t.tables {
+		s.Buckets += len(t.tables[n])
+		for _, i := range t.tables[n] {
+			if i == nil {
+				s.EmptyBuckets++
+			} else {
+				s.record(n + 1)
+			}
+		}
+	}
+	for n := range t.stash {
+		s.record(n + 3)
+	}
+}
+
+// the buckets of the second table follow those of the first, and the stash is
+// the bucket after them.
+func (t *cuckooTable) chain(i *item) (int, int) {
+	size := len(t.tables[0])
+	for n := range t.tables {
+		if p := t.pos(n, i.hash); t.tables[n][p] == i {
+			return n*size + p, n + 1
+		}
+	}
+	for n, v := range t.stash {
+		if v == i {
+			return 2 * size, n + 3
+		}
+	}
+	return 0, 0
+}
+
+// the stash is shown as one more bucket after the tables.
+func (t *cuckooTable) layout(f func(items []*item)) {
+	for n := range t.tables {
+		for _, i := range t.tables[n] {
+			if i == nil {
+				f(nil)
+			} else {
+				f([]*item{i})
+			}
+		}
+	}
+	f(t.stash)
+}
+
+func (t *cuckooTable) cursor() cursor {
+	return &cuckooCursor{t: t, stash: -1}
+}
+
+type cuckooCursor struct {
+	t *cuckooTable
+	// the next slot to look at, counting across both tables.
+	pos int
+	// the last stash index returned. The stash is walked backwards, so
+	// deleting the item last returned does not move the ones still to come.
+	stash int
+}
+
+func (c *cuckooCursor) next() *item {
+	size := len(c.t.tables[0])
+	for c.pos < 2*size {
+		i := c.t.tables[c.pos/size][c.pos%size]
+		c.pos++
+		if i != nil {
+			return i
+		}
+	}
+	if c.stash < 0 {
+		c.stash = len(c.t.stash)
+	}
+	if c.stash == 0 {
+		return nil
+	}
+	c.stash--
+	return c.t.stash[c.stash]
+}
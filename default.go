@@ -0,0 +1,19 @@
+package dictionary
+
+// FactoryFunc creates the default value for a missing key.
+type FactoryFunc func(key Hasher) interface{}
+
+// SetDefault sets a factory used by Get to create missing entries. The
+// created value is stored and returned, like Python's defaultdict.
+func SetDefault(factory FactoryFunc) OptionsFunc {
+	return func(d *Dictionary) {
+		d.factory = factory
+	}
+}
+
+// NewDefault creates a dictionary where Get on a missing key calls factory,
+// stores the result, and returns it. Other options can be set by passing in
+// OptionsFunc.
+func NewDefault(factory FactoryFunc, options ...OptionsFunc) *Dictionary {
+	return New(append([]OptionsFunc{SetDefault(factory)}, options...)...)
+}
@@ -1,24 +1,147 @@
 // Package dictionary implements a hash/map/dictionary for educational purposes.
+//
+// A Dictionary is not safe for concurrent use, and its methods never take its
+// lock themselves. Code sharing a dictionary between goroutines holds Lock
+// around every call, including to Snapshot and Txn. GetWait, like
+// sync.Cond.Wait, releases the lock while it waits, and takes it again before
+// returning. The exceptions are the janitor and the expvar.Var returned by Var,
+// which run on goroutines of their own and take the lock while they use the
+// dictionary.
 package dictionary
 
-import "container/list"
+import (
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
 
 type (
 	// Dictionary is a simple hashed dictionary. It is intended
 	// to only store a single type, but does not enforce this explicitly.
 	// It is not safe for concurrent use, so users should implement
-	// their own locking.
+	// their own locking, such as with Lock and Unlock.
 	Dictionary struct {
 		numBuckets uint32
-		// just use a simple list for our bucket
-		// this is not meant for very high performance, just as an example.
-		buckets []*list.List
+		count      int
+		backend    Backend
+		store      store
+		// the number of entries to size the buckets for, if set.
+		capacity int
+		// shrink when the number of entries falls below this fraction of
+		// the capacity, if set.
+		shrinkFraction float64
+		// grow as entries are added, a few at a time.
+		incremental bool
+		// the number of walks in progress.
+		walking int
+		// creates the buckets of a Chaining dictionary, if set.
+		bucketFactory BucketFactory
+		// the order Each and Keys visit entries in.
+		order Order
+		// the ends of the list of items in the order they were added, with
+		// InsertionOrder.
+		oldest, newest *item
+		// the items with the smallest and largest keys, once Min and Max
+		// have looked for them.
+		extremes [2]extremeItem
+
+		// called when an item is added to a chain longer than
+		// chainThreshold.
+		chainThreshold int
+		chainWarning   ChainWarningFunc
+
+		// lookups by Get and Contains.
+		hits     uint64
+		misses   uint64
+		observer Observer
+
+		// called when entries change.
+		onInsert  HookFunc
+		onReplace ReplaceHookFunc
+		onDelete  HookFunc
+		onEvict   EvictFunc
+
+		// channels returned by Watch.
+		watchMu  sync.Mutex
+		watchers map[*watcher]struct{}
+		// the number of watchers, read without holding watchMu.
+		numWatchers int32
+
+		// seed for keys that implement SeededHasher.
+		seed maphash.Seed
+		// overrides the hash of StringKey keys, if set.
+		stringHash StringHashFunc
+		// applied to keys before they are used, if set.
+		normalizer NormalizeFunc
+
+		keyEncoder KeyEncoder
+		keyDecoder KeyDecoder
+		keyCodec   KeyCodec
+		// used by LoadFrom and WriteTo, if set.
+		textSeparator string
+		parseValue    ValueParser
+		formatValue   ValueFormatter
+
+		// sorted StringKey keys, if WithPrefixIndex is set.
+		prefixes *prefixIndex
+		// every item, if WithRandomAccess is set.
+		random *randomIndex
+		// the hashes of the items, if WithBloomFilter is set.
+		bloom      *bloomFilter
+		bloomItems int
+		bloomRate  float64
+
+		// for bounded dictionaries.
+		maxEntries int
+		maxWeight  int
+		weight     int
+		weigher    Weigher
+		policy     EvictionPolicy
+		// the admission filter, if WithTinyLFU is set.
+		tinyLFU bool
+		sketch  *frequencySketch
+
+		factory FactoryFunc
+
+		// number of items with an expiration time.
+		expiring int
+		// renew the expiration time of items when they are used.
+		sliding bool
+		// returns the current time, if set, rather than time.Now.
+		clock func() time.Time
+
+		// reused items, if pooling is enabled.
+		items *sync.Pool
+		// slabs of items, if WithArena is set.
+		arena *arena
+
+		mu              sync.Mutex
+		janitorInterval time.Duration
+		janitor         *janitor
 	}
 
 	item struct {
 		key   Hasher
-		hash  uint32
+		hash  uint64
 		value interface{}
+		// as given by the weigher, if any.
+		weight int
+		// zero if the item does not expire.
+		expires time.Time
+		// the TTL the expiration time was set from, renewed on use with
+		// WithSlidingExpiration.
+		ttl time.Duration
+		// the position in the random access index, if there is one.
+		pos int
+		// the items added before and after this one, with InsertionOrder.
+		prev, next *item
+	}
+
+	// Item is a key/value pair, used by bulk operations.
+	Item struct {
+		Key   Hasher
+		Value interface{}
 	}
 
 	// OptionsFunc is used to set options when creating a new dictionary.
@@ -28,6 +151,34 @@ type (
 	// returning a non-nil error will cause iteration to stop
 	EachFunc func(Hasher, interface{}) error
 
+	// EqualFunc reports whether two values are equal.
+	EqualFunc func(a, b interface{}) bool
+
+	// LessFunc reports whether key a should sort before key b.
+	LessFunc func(a, b Hasher) bool
+
+	// MergeFunc is used by Merge to resolve a key present in both
+	// dictionaries. It is passed the current value and the value from the
+	// other dictionary, and returns the value to keep.
+	MergeFunc func(key Hasher, a, b interface{}) interface{}
+
+	// UpdateFunc is the function called by Update. It is passed the current
+	// value, if any, and whether the key exists. It returns the new value
+	// and whether the key should be deleted instead.
+	UpdateFunc func(old interface{}, exists bool) (interface{}, bool)
+
+	// PredicateFunc reports whether an entry should be selected, such as by
+	// Filter.
+	PredicateFunc func(key Hasher, val interface{}) bool
+
+	// MapFunc is the function called by MapValues. It returns the new value
+	// for an entry.
+	MapFunc func(key Hasher, val interface{}) interface{}
+
+	// ReduceFunc is the function called by Reduce. It is passed the result
+	// so far and an entry, and returns the new result.
+	ReduceFunc func(acc interface{}, key Hasher, val interface{}) interface{}
+
 	// Hasher defines interface for keys to be stored in a dictionary.
 	Hasher interface {
 		// Hash should return a hash of the key. Ideally, this should create
@@ -36,26 +187,106 @@ type (
 		// Equal must return true if the receiver is equal to the argument.
 		Equal(interface{}) bool
 	}
+
+	// SeededHasher may be implemented by keys that can be hashed with a
+	// seed. Each dictionary uses its own random seed, so an attacker cannot
+	// choose keys that all land in the same bucket. It is preferred over
+	// Hash64 and Hash when available.
+	SeededHasher interface {
+		Hasher
+		// HashSeed should return a hash of the key using seed, such as
+		// with hash/maphash. Keys that are Equal must have the same hash for
+		// a given seed.
+		HashSeed(seed maphash.Seed) uint64
+	}
+
+	// Hasher64 may be implemented by keys that can produce a 64 bit hash.
+	// It is preferred over Hash when available, as it leads to fewer
+	// collisions for large numbers of keys.
+	Hasher64 interface {
+		Hasher
+		// Hash64 should return a 64 bit hash of the key. Keys that are
+		// Equal must have the same hash.
+		Hash64() uint64
+	}
 )
 
-// New creates a new dictionary. Options can be set by passing in OptionsFunc
+// 31 is a good choice for a few dozen to a couple hundred keys.
+// We could dynamically resize the number of buckets, but that increases
+// the complexity.
+const defaultBuckets = 31
+
+// New creates a new dictionary. Options can be set by passing in OptionsFunc.
+// A zero number of buckets is replaced with the default; use NewWithError to
+// have invalid options reported instead.
 func New(options ...OptionsFunc) *Dictionary {
+	d := newDictionary(options)
+	d.start()
+	return d
+}
+
+// helper to create a dictionary with the options applied, but not yet
+// started.
+func newDictionary(options []OptionsFunc) *Dictionary {
 	d := &Dictionary{
-		// 31 is a good choice for a few dozen to a couple hundred keys.
-		// We could dynamically resize the number of buckets, but that increases
-		// the complexity.
-		numBuckets: 31,
+		numBuckets: defaultBuckets,
 	}
 
 	for _, f := range options {
 		f(d)
 	}
+	return d
+}
 
-	d.buckets = make([]*list.List, d.numBuckets)
-	for i := 0; uint32(i) < d.numBuckets; i++ {
-		d.buckets[i] = list.New()
+// helper to allocate the store and start the janitor, if any, once the options
+// have been applied.
+func (d *Dictionary) start() {
+	if n := d.bucketsFor(d.capacity); n > d.numBuckets {
+		d.numBuckets = n
+	}
+
+	if (d.maxEntries > 0 || d.maxWeight > 0) && d.policy == nil {
+		d.policy = NewLRUPolicy()
+	}
+	if d.tinyLFU && d.maxEntries > 0 {
+		d.sketch = newFrequencySketch(d.maxEntries)
+	}
+
+	d.init()
+	if d.janitorInterval > 0 {
+		d.startJanitor()
+	}
+}
+
+// helper to allocate empty buckets once the options have been applied. A zero
+// Dictionary, such as one being unmarshaled into, uses the defaults.
+func (d *Dictionary) init() {
+	if d.numBuckets == 0 {
+		d.numBuckets = defaultBuckets
+	}
+	if d.seed == (maphash.Seed{}) {
+		d.seed = maphash.MakeSeed()
+	}
+	d.count = 0
+	d.store = d.newStore(d.numBuckets)
+	if d.prefixes != nil {
+		d.prefixes = &prefixIndex{}
+	}
+	if d.random != nil {
+		d.random = &randomIndex{}
+	}
+	d.oldest, d.newest = nil, nil
+	d.extremes = [2]extremeItem{}
+	d.bloom = d.newBloomFilter()
+}
+
+// SetHashSeed sets the seed used for keys that implement SeededHasher,
+// rather than a random one. This makes the bucket layout repeatable, but
+// removes the protection against chosen keys.
+func SetHashSeed(seed maphash.Seed) OptionsFunc {
+	return func(d *Dictionary) {
+		d.seed = seed
 	}
-	return d
 }
 
 // SetBuckets will set the number of hash buckets.
@@ -65,103 +296,479 @@ func SetBuckets(n uint32) func(d *Dictionary) {
 	}
 }
 
-func (d *Dictionary) getBucket(key Hasher) (uint32, *list.List) {
-	h := key.Hash()
-	n := h % d.numBuckets
-	return h, d.buckets[n]
+// helper to hash a key, preferring a seeded hash and then a 64 bit hash if
+// the key supports them. StringKey keys, even wrapped by CachedKey, use the
+// string hash, if one is set.
+func (d *Dictionary) hash(key Hasher) uint64 {
+	if key == nil {
+		panic(ErrNilKey)
+	}
+	return hashWith(key, d.seed, d.stringHash)
+}
+
+// hashWith is the hash used by a dictionary with the given seed and string
+// hash, so it can be shared with Frozen.
+func hashWith(key Hasher, seed maphash.Seed, stringHash StringHashFunc) uint64 {
+	if stringHash != nil {
+		k := key
+		if c, ok := k.(*CachedHasher); ok {
+			k = c.Key
+		}
+		if s, ok := k.(StringKey); ok {
+			return stringHash(string(s))
+		}
+	}
+
+	return hashKey(key, seed)
+}
+
+// hashKey hashes a key, preferring a seeded hash and then a 64 bit hash if
+// the key supports them.
+func hashKey(key Hasher, seed maphash.Seed) uint64 {
+	switch k := key.(type) {
+	case SeededHasher:
+		return k.HashSeed(seed)
+	case Hasher64:
+		return k.Hash64()
+	}
+	return uint64(key.Hash())
 }
 
-// Set adds an item to the dictionary. It will replace any existing value.
+// Set adds an item to the dictionary. It will replace any existing value,
+// and the entry will no longer expire if it had a TTL. An admission filter,
+// such as WithTinyLFU, may turn a new key away; use TrySet to find out.
 func (d *Dictionary) Set(key Hasher, val interface{}) {
-	h, bucket := d.getBucket(key)
+	d.set(key, val, time.Time{}, 0)
+}
+
+// TrySet is like Set, but reports whether the value was stored, which is only
+// false if an admission filter, such as WithTinyLFU, turned a new key away.
+func (d *Dictionary) TrySet(key Hasher, val interface{}) bool {
+	_, _, stored := d.set(key, val, time.Time{}, 0)
+	return stored
+}
+
+// helper to set a value, returning the replaced value, if any, and whether the
+// value was stored. The entry expires at expires, if set, which is ttl from
+// now.
+func (d *Dictionary) set(key Hasher, val interface{}, expires time.Time, ttl time.Duration) (interface{}, bool, bool) {
+	key, h, i := d.lookup(key)
+	return d.setItem(key, h, i, val, expires, ttl)
+}
+
+// helper for set, with the key already looked up. i is the item for key, or
+// nil if it is not present.
+func (d *Dictionary) setItem(key Hasher, h uint64, i *item, val interface{}, expires time.Time, ttl time.Duration) (interface{}, bool, bool) {
+	d.recordAccess(h)
+
+	if i != nil {
+		prev := i.value
+		d.setExpires(i, expires, ttl)
+		d.replace(i, val)
+		return prev, true, true
+	}
+
+	// key not found, so add it
+	i = d.newItem(key, h, val)
+	i.expires = expires
+	i.ttl = ttl
+	return nil, false, d.add(i)
+}
+
+// helper to find the item for a key. The normalized key and its hash are
+// returned as well, so callers can insert without hashing the key again.
+func (d *Dictionary) lookup(key Hasher) (Hasher, uint64, *item) {
+	key = d.normalize(key)
+	h := d.hash(key)
+	return key, h, d.find(key, h)
+}
+
+// helper to find the item for a key with an already computed hash. If the
+// item has expired, it is removed and treated as missing. Keys the Bloom
+// filter, if any, has never seen are not looked for.
+func (d *Dictionary) find(key Hasher, h uint64) *item {
+	if d.bloom != nil && !d.bloom.mayContain(h) {
+		return nil
+	}
+	i := d.store.find(key, h)
+	if i != nil && !i.expires.IsZero() && i.expired(d.now()) {
+		d.expire(i)
+		return nil
+	}
+	return i
+}
+
+// helper to call f on each item until it returns false. Expired items are
+// removed rather than passed to f. f may remove the item it is passed.
+func (d *Dictionary) walk(f func(i *item) bool) {
+	// a zero Dictionary, such as one being unmarshaled into, has no store
+	// yet.
+	if d.store == nil {
+		return
+	}
+	d.walking++
+	defer func() { d.walking-- }()
 
-	i := &item{
-		hash:  h,
-		key:   key,
-		value: val,
+	var now time.Time
+	if d.expiring > 0 {
+		now = d.now()
 	}
 
-	for e := bucket.Front(); e != nil; e = e.Next() {
-		v := e.Value.(*item)
-		// check the hash value first. If these are not equal, then the keys cannot be equal.
-		if v.hash == h && key.Equal(v.key) {
-			// replace. in future, we could return the replaced value.
-			e.Value = i
+	c := d.store.cursor()
+	for i := c.next(); i != nil; i = c.next() {
+		if i.expired(now) {
+			d.expire(i)
+		} else if !f(i) {
 			return
 		}
 	}
+}
 
-	// key not found, so add it
-	bucket.PushFront(i)
+// helper to add an item that is known not to be present. It reports whether
+// the item was admitted; if not, it is freed.
+func (d *Dictionary) add(i *item) bool {
+	if !d.admit(i) {
+		if d.onEvict != nil {
+			d.onEvict(i.key, i.value, EvictRejected)
+		}
+		d.freeItem(i)
+		return false
+	}
+	d.resizeStep()
+	if d.observer != nil {
+		d.observer.Set(i.key)
+		// backends that grow on their own do so while inserting.
+		capacity := d.store.capacity()
+		defer func() {
+			if c := d.store.capacity(); c != capacity {
+				d.observer.Rehash(c)
+			}
+		}()
+	}
+	d.store.insert(i)
+	d.count++
+	if d.bloom != nil {
+		d.bloom.add(i.hash)
+		if d.bloom.stale() {
+			d.rebuildBloom()
+		}
+	}
+	if d.chainWarning != nil {
+		d.checkChain(i)
+	}
+	if d.prefixes != nil {
+		d.prefixes.insert(i)
+	}
+	if d.random != nil {
+		d.random.insert(i)
+	}
+	if d.order == InsertionOrder {
+		d.pushBack(i)
+	}
+	d.addExtreme(i)
+	if !i.expires.IsZero() {
+		d.expiring++
+	}
+	i.weight = 0
+	if d.weigher != nil {
+		i.weight = d.weigher(i.key, i.value)
+		d.weight += i.weight
+	}
+	if d.onInsert != nil {
+		d.onInsert(i.key, i.value)
+	}
+	if d.watched() {
+		d.notify(EventSet, i, i.value)
+	}
+	if d.policy != nil {
+		d.policy.Touch(i.key)
+		d.evict()
+	}
+	return true
 }
 
-// helper to get the list and element.
-func (d *Dictionary) getElement(key Hasher) (*list.List, *list.Element) {
-	h, bucket := d.getBucket(key)
-	for e := bucket.Front(); e != nil; e = e.Next() {
-		v := e.Value.(*item)
-		if v.hash == h && key.Equal(v.key) {
-			return bucket, e
+// helper to remove an item we already have, without comparing keys. The item
+// must not be used afterwards, as it may be reused.
+func (d *Dictionary) remove(i *item) {
+	d.count--
+	d.store.delete(i)
+	if d.bloom != nil {
+		d.bloom.removed++
+		if d.bloom.stale() {
+			d.rebuildBloom()
 		}
 	}
-	return nil, nil
+	if d.prefixes != nil {
+		d.prefixes.delete(i)
+	}
+	if d.random != nil {
+		d.random.delete(i)
+	}
+	if d.order == InsertionOrder {
+		d.unlink(i)
+	}
+	d.removeExtreme(i)
+	if !i.expires.IsZero() {
+		d.expiring--
+	}
+	d.weight -= i.weight
+	if d.policy != nil {
+		d.policy.Remove(i.key)
+	}
+	if d.onDelete != nil {
+		d.onDelete(i.key, i.value)
+	}
+	if d.watched() {
+		d.notify(EventDelete, i, i.value)
+	}
+	d.freeItem(i)
+	d.resizeStep()
+	d.autoShrink()
+}
+
+// helper to replace the value of an item already in the dictionary.
+func (d *Dictionary) replace(i *item, val interface{}) {
+	if d.observer != nil {
+		d.observer.Set(i.key)
+	}
+	old := i.value
+	i.value = val
+	if d.onReplace != nil {
+		d.onReplace(i.key, old, val)
+	}
+	if d.watched() {
+		d.notify(EventSet, i, val)
+	}
+	d.touch(i)
+	if d.weigher != nil {
+		d.weight -= i.weight
+		i.weight = d.weigher(i.key, i.value)
+		d.weight += i.weight
+		d.evict()
+	}
+}
+
+// helper to remove all of the items, so any bookkeeping such as eviction
+// policies sees them go.
+func (d *Dictionary) clear() {
+	d.walk(func(i *item) bool {
+		d.delete(i)
+		return true
+	})
 }
 
 // Get returns an item from the dictionary. The second return value will be
-// false if not found.
+// false if not found. If the dictionary has a default factory, missing
+// entries are created instead; if an admission filter turns the created entry
+// away, its value is still returned, but the second return value is false.
 func (d *Dictionary) Get(key Hasher) (interface{}, bool) {
-	bucket, e := d.getElement(key)
-	if bucket == nil || e == nil {
+	key, h, i := d.lookup(key)
+	d.recordAccess(h)
+	if i == nil {
+		d.miss(key)
+		if d.factory != nil {
+			val := d.factory(key)
+			return val, d.add(d.newItem(key, h, val))
+		}
 		return nil, false
 	}
-	return e.Value.(*item).value, true
+	d.hit(key)
+	d.touch(i)
+	return i.value, true
 
 }
 
+// Contains reports whether key is present in the dictionary.
+func (d *Dictionary) Contains(key Hasher) bool {
+	key, _, i := d.lookup(key)
+	if i == nil {
+		d.miss(key)
+		return false
+	}
+	d.hit(key)
+	return true
+}
+
 // Delete removes an item from the dictionary.  Returns the deleted value.
 func (d *Dictionary) Delete(key Hasher) (interface{}, bool) {
-	bucket, e := d.getElement(key)
-	if bucket == nil || e == nil {
+	_, _, i := d.lookup(key)
+	if i == nil {
 		return nil, false
 	}
-	v := e.Value.(*item).value
-	bucket.Remove(e)
-	return v, true
+	val := i.value
+	d.delete(i)
+	return val, true
+}
+
+// Clear removes all of the entries. With WithArena, the slabs holding them are
+// released as well.
+func (d *Dictionary) Clear() {
+	d.clear()
+	if d.arena != nil {
+		d.arena.reset()
+	}
+}
+
+// Pop removes key from the dictionary and returns its value. If the key is not
+// present, def is returned.
+func (d *Dictionary) Pop(key Hasher, def interface{}) interface{} {
+	if v, ok := d.Delete(key); ok {
+		return v
+	}
+	return def
+}
+
+// PopItem removes an arbitrary entry from the dictionary and returns it. The
+// last return value will be false if the dictionary is empty.
+func (d *Dictionary) PopItem() (Hasher, interface{}, bool) {
+	var found *item
+	d.walk(func(i *item) bool {
+		found = i
+		return false
+	})
+	if found == nil {
+		return nil, nil, false
+	}
+	key, val := found.key, found.value
+	d.delete(found)
+	return key, val, true
+}
+
+// GetOrSet returns the existing value for key if present. Otherwise, it adds
+// val and returns it. The second return value will be true if the key was
+// already present. The key is only hashed and looked up once. If an admission
+// filter turns the key away, val is returned without being stored; use
+// SetIfAbsent to find out whether it was.
+func (d *Dictionary) GetOrSet(key Hasher, val interface{}) (interface{}, bool) {
+	v, loaded, _ := d.getOrSet(key, val)
+	return v, loaded
+}
+
+// helper for GetOrSet, which also reports whether val was stored.
+func (d *Dictionary) getOrSet(key Hasher, val interface{}) (interface{}, bool, bool) {
+	key, h, i := d.lookup(key)
+	d.recordAccess(h)
+	if i != nil {
+		d.touch(i)
+		return i.value, true, false
+	}
+	return val, false, d.add(d.newItem(key, h, val))
+}
+
+// SetIfAbsent adds val only if key is not already present. It returns true
+// if the value was added.
+func (d *Dictionary) SetIfAbsent(key Hasher, val interface{}) bool {
+	_, _, stored := d.getOrSet(key, val)
+	return stored
+}
+
+// Update sets the value for key to the result of calling f with the current
+// value. If f asks for the key to be deleted, it is removed instead. The key is
+// only hashed and looked up once. Update returns the resulting value and
+// whether the key is present afterwards, which is false, with a nil value, if
+// an admission filter turned a new key away.
+func (d *Dictionary) Update(key Hasher, f UpdateFunc) (interface{}, bool) {
+	key, h, i := d.lookup(key)
+
+	var old interface{}
+	if i != nil {
+		old = i.value
+	}
+
+	val, del := f(old, i != nil)
+	switch {
+	case del:
+		if i != nil {
+			d.delete(i)
+		}
+		return nil, false
+	case i != nil:
+		d.replace(i, val)
+	default:
+		if !d.add(d.newItem(key, h, val)) {
+			return nil, false
+		}
+	}
+	return val, true
 }
 
 // Each executes the function on each element. Error returned will be
-// any error the EachFunc returned to stop iteration
+// any error the EachFunc returned to stop iteration. f may delete the entry it
+// is passed, but must not otherwise add or remove entries; use EachSafe for
+// that.
 func (d *Dictionary) Each(f EachFunc) error {
-	for _, bucket := range d.buckets {
-		for e := bucket.Front(); e != nil; e = e.Next() {
-			i := e.Value.(*item)
-			if err := f(i.key, i.value); err != nil {
-				return err
-			}
+	var err error
+	d.iterate(func(i *item) bool {
+		err = f(i.key, i.value)
+		return err == nil
+	})
+
+	return err
+}
+
+// EachContext executes the function on each element, like Each, but stops
+// early if ctx is done, returning its error. The context is checked before
+// each entry.
+func (d *Dictionary) EachContext(ctx context.Context, f EachFunc) error {
+	var err error
+	d.iterate(func(i *item) bool {
+		if err = ctx.Err(); err != nil {
+			return false
 		}
-	}
+		err = f(i.key, i.value)
+		return err == nil
+	})
+
+	return err
+}
 
+// EachSafe executes the function on each element, like Each, but iterates
+// over a copy of the entries, so f may add, change, or delete any entries.
+// Every entry present when EachSafe is called is passed to f, with the value
+// it had then, even if f has since deleted it.
+func (d *Dictionary) EachSafe(f EachFunc) error {
+	items := make([]Item, 0, d.Len())
+	d.iterate(func(i *item) bool {
+		items = append(items, Item{Key: i.key, Value: i.value})
+		return true
+	})
+	for _, i := range items {
+		if err := f(i.Key, i.Value); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Len returns the number of entries in the dictionary.
+func (d *Dictionary) Len() int {
+	// expired items should not be counted.
+	d.removeExpired()
+	return d.count
+}
+
 // Keys returns all the keys in the hash
 func (d *Dictionary) Keys() []Hasher {
-	// first calculate the length
-	len := 0
-	for _, bucket := range d.buckets {
-		len = len + bucket.Len()
-	}
-	keys := make([]Hasher, len)
-
-	i := 0
-	for _, bucket := range d.buckets {
-		for e := bucket.Front(); e != nil; e = e.Next() {
-			keys[i] = e.Value.(*item).key
-			i++
-		}
-	}
-	return keys
+	return d.AppendKeys(make([]Hasher, 0, d.Len()))
+}
+
+// AppendKeys appends all the keys to dst and returns the extended slice, in
+// the same order as Keys. Passing dst[:0] reuses its storage, so code that
+// lists the keys repeatedly need not allocate each time.
+func (d *Dictionary) AppendKeys(dst []Hasher) []Hasher {
+	d.iterate(func(i *item) bool {
+		dst = append(dst, i.key)
+		return true
+	})
+	return dst
 }
 
-// TODO: add a fucntion which returns the distribution? could help user tune
-// number of buckets. Also perhaps interesting for metrics/testing.
+// AppendValues appends all the values to dst and returns the extended slice,
+// in the same order as Keys and AppendKeys.
+func (d *Dictionary) AppendValues(dst []interface{}) []interface{} {
+	d.iterate(func(i *item) bool {
+		dst = append(dst, i.value)
+		return true
+	})
+	return dst
+}
@@ -1,10 +1,24 @@
 package dictionary_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
+	"hash/maphash"
 	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bakins/dictionary"
 	"github.com/stretchr/testify/require"
@@ -23,6 +37,9 @@ func TestSimpleSet(t *testing.T) {
 	v, ok = d.Get(dictionary.StringKey("bar"))
 	require.Nil(t, v)
 	require.Equal(t, false, ok, "should not have found key")
+
+	require.Equal(t, true, d.Contains(k), "should have found key")
+	require.Equal(t, false, d.Contains(dictionary.StringKey("bar")), "should not have found key")
 }
 
 type entry struct {
@@ -140,6 +157,34 @@ func TestIntSet(t *testing.T) {
 	}
 }
 
+type wideKey uint64
+
+func (k wideKey) Hash() uint32 {
+	// deliberately terrible, the dictionary should use Hash64.
+	return 0
+}
+
+func (k wideKey) Hash64() uint64 {
+	return uint64(k)
+}
+
+func (k wideKey) Equal(v interface{}) bool {
+	return k == v.(wideKey)
+}
+
+func TestHasher64(t *testing.T) {
+	d := dictionary.New(dictionary.SetBuckets(2))
+
+	d.Set(wideKey(1), "one")
+	d.Set(wideKey(1<<40), "big")
+	d.Set(wideKey(1<<40+1), "bigger")
+
+	v, ok := d.Get(wideKey(1 << 40))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "big", v.(string), "unexpected value")
+	require.Equal(t, 3, d.Len(), "unexpected length")
+}
+
 func TestEach(t *testing.T) {
 	d := dictionary.New()
 
@@ -168,6 +213,1533 @@ func TestEach(t *testing.T) {
 
 }
 
+func TestAppendKeys(t *testing.T) {
+	d := dictionary.New(dictionary.WithOrder(dictionary.DeterministicOrder))
+	for n := 0; n < 3; n++ {
+		d.Set(dictionary.Int64Key(n), n*10)
+	}
+
+	keys := d.AppendKeys([]dictionary.Hasher{dictionary.StringKey("first")})
+	require.Equal(t, []dictionary.Hasher{dictionary.StringKey("first"), dictionary.Int64Key(0), dictionary.Int64Key(1), dictionary.Int64Key(2)}, keys)
+
+	values := d.AppendValues(nil)
+	require.Equal(t, []interface{}{0, 10, 20}, values)
+
+	// a buffer with room for the keys is reused.
+	reused := d.AppendKeys(keys[:0])
+	require.Equal(t, 3, len(reused))
+	require.Equal(t, &keys[0], &reused[0], "should reuse the buffer")
+}
+
+func TestEachContext(t *testing.T) {
+	d := dictionary.New()
+	for n := 0; n < 100; n++ {
+		d.Set(dictionary.Int64Key(n), n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	seen := 0
+	err := d.EachContext(ctx, func(dictionary.Hasher, interface{}) error {
+		seen++
+		if seen == 10 {
+			cancel()
+		}
+		return nil
+	})
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, 10, seen, "iteration should stop once canceled")
+
+	seen = 0
+	require.Nil(t, d.EachContext(context.Background(), func(dictionary.Hasher, interface{}) error {
+		seen++
+		return nil
+	}))
+	require.Equal(t, 100, seen, "every entry should be passed")
+}
+
+func TestEachParallel(t *testing.T) {
+	d := dictionary.New()
+	for n := 0; n < 1000; n++ {
+		d.Set(dictionary.Int64Key(n), n)
+	}
+
+	var mu sync.Mutex
+	sum := 0
+	err := d.EachParallel(4, func(_ dictionary.Hasher, v interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sum += v.(int)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 999*1000/2, sum, "every entry should be passed once")
+
+	errOdd := errors.New("odd")
+	err = d.EachParallel(0, func(_ dictionary.Hasher, v interface{}) error {
+		if v.(int)%2 == 1 {
+			return errOdd
+		}
+		return nil
+	})
+	require.True(t, errors.Is(err, errOdd), "errors should be returned")
+}
+
+func TestEachParallelUneven(t *testing.T) {
+	for _, size := range []int{1, 3, 5, 7, 9} {
+		d := dictionary.New()
+		for n := 0; n < size; n++ {
+			d.Set(dictionary.Int64Key(n), n)
+		}
+
+		for _, workers := range []int{2, 4, 8, 16} {
+			var mu sync.Mutex
+			seen := 0
+			err := d.EachParallel(workers, func(dictionary.Hasher, interface{}) error {
+				mu.Lock()
+				defer mu.Unlock()
+				seen++
+				return nil
+			})
+			require.Nil(t, err)
+			require.Equal(t, size, seen, "%d entries, %d workers", size, workers)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	var want []dictionary.Hasher
+	for n := 0; n < 100; n++ {
+		want = append(want, dictionary.Int64Key(n))
+	}
+
+	d := dictionary.New(dictionary.WithOrder(dictionary.DeterministicOrder))
+	for _, n := range rand.Perm(100) {
+		d.Set(dictionary.Int64Key(n), n)
+	}
+	require.Equal(t, want, d.Keys(), "keys should be sorted")
+
+	// keys of different types are not compared with each other.
+	d.Set(dictionary.StringKey("a"), "a")
+	mixed := d.Keys()
+	require.Equal(t, 101, len(mixed), "unexpected number of keys")
+	require.Equal(t, mixed, d.Keys(), "order should not change between calls")
+
+	d = dictionary.New(dictionary.WithOrder(dictionary.RandomOrder))
+	for n := 0; n < 100; n++ {
+		d.Set(dictionary.Int64Key(n), n)
+	}
+	first := d.Keys()
+	require.ElementsMatch(t, want, first)
+	different := false
+	for n := 0; n < 10 && !different; n++ {
+		different = !reflect.DeepEqual(first, d.Keys())
+	}
+	require.True(t, different, "order should change between calls")
+}
+
+func TestInsertionOrder(t *testing.T) {
+	d := dictionary.New(dictionary.WithOrder(dictionary.InsertionOrder))
+	_, _, ok := d.Oldest()
+	require.Equal(t, false, ok, "empty dictionary should have no entry")
+
+	var want []dictionary.Hasher
+	for _, n := range rand.Perm(20) {
+		d.Set(dictionary.Int64Key(n), n)
+		want = append(want, dictionary.Int64Key(n))
+	}
+	// replacing a value keeps its place.
+	d.Set(want[5], -1)
+	require.Equal(t, want, d.Keys(), "keys should be in insertion order")
+
+	k, _, _ := d.Oldest()
+	require.Equal(t, want[0], k, "unexpected oldest key")
+	k, _, _ = d.Newest()
+	require.Equal(t, want[19], k, "unexpected newest key")
+
+	require.Equal(t, true, d.MoveToBack(want[0]), "should have moved key")
+	k, _, _ = d.Newest()
+	require.Equal(t, want[0], k, "moved key should be newest")
+	require.Equal(t, false, d.MoveToBack(dictionary.Int64Key(100)), "missing key should not be moved")
+
+	k, v, ok := d.PopOldest()
+	require.Equal(t, true, ok, "should have popped an entry")
+	require.Equal(t, want[1], k, "unexpected oldest key")
+	require.Equal(t, int(k.(dictionary.Int64Key)), v.(int), "unexpected value")
+	require.Equal(t, 19, d.Len(), "unexpected length")
+
+	// deleting while iterating must not skip any.
+	seen := 0
+	require.Nil(t, d.Each(func(k dictionary.Hasher, _ interface{}) error {
+		seen++
+		d.Delete(k)
+		return nil
+	}))
+	require.Equal(t, 19, seen, "every entry should be passed once")
+	require.Equal(t, 0, d.Len(), "unexpected length")
+	_, _, ok = d.Newest()
+	require.Equal(t, false, ok, "empty dictionary should have no entry")
+}
+
+func TestEachSafe(t *testing.T) {
+	d := dictionary.New()
+	for n := 0; n < 100; n++ {
+		d.Set(dictionary.Int64Key(n), n)
+	}
+
+	seen := 0
+	err := d.EachSafe(func(k dictionary.Hasher, v interface{}) error {
+		seen++
+		n := int(k.(dictionary.Int64Key))
+		d.Delete(dictionary.Int64Key(99 - n))
+		d.Set(dictionary.Int64Key(n+100), v)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 100, seen, "every entry should be passed once")
+	require.Equal(t, 100, d.Len(), "unexpected length")
+	for n := 0; n < 100; n++ {
+		require.Equal(t, false, d.Contains(dictionary.Int64Key(n)), "key should have been deleted")
+	}
+}
+
+func TestGetOrSet(t *testing.T) {
+	d := dictionary.New()
+	k := dictionary.StringKey("foo")
+
+	v, ok := d.GetOrSet(k, "bar")
+	require.Equal(t, false, ok, "should not have found key")
+	require.Equal(t, "bar", v.(string), "unexpected value")
+
+	v, ok = d.GetOrSet(k, "baz")
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "bar", v.(string), "unexpected value")
+
+	require.Equal(t, false, d.SetIfAbsent(k, "baz"), "should not have replaced value")
+	require.Equal(t, true, d.SetIfAbsent(dictionary.StringKey("baz"), "baz"), "should have added value")
+
+	v, _ = d.Get(k)
+	require.Equal(t, "bar", v.(string), "unexpected value")
+}
+
+func TestUpdate(t *testing.T) {
+	d := dictionary.New()
+	k := dictionary.StringKey("foo")
+
+	incr := func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return 1, false
+		}
+		return old.(int) + 1, false
+	}
+
+	for i := 1; i <= 3; i++ {
+		v, ok := d.Update(k, incr)
+		require.Equal(t, true, ok, "should have found key")
+		require.Equal(t, i, v.(int), "unexpected value")
+	}
+
+	v, ok := d.Update(k, func(old interface{}, exists bool) (interface{}, bool) {
+		require.Equal(t, true, exists, "should have found key")
+		require.Equal(t, 3, old.(int), "unexpected value")
+		return nil, true
+	})
+	require.Nil(t, v)
+	require.Equal(t, false, ok, "should have deleted key")
+
+	v, ok = d.Get(k)
+	require.Nil(t, v)
+	require.Equal(t, false, ok, "should not have found key")
+}
+
+func TestBulk(t *testing.T) {
+	d := dictionary.New()
+
+	d.SetMany([]dictionary.Item{
+		{Key: dictionary.StringKey("a"), Value: 1},
+		{Key: dictionary.StringKey("b"), Value: 2},
+		{Key: dictionary.StringKey("c"), Value: 3},
+	})
+
+	keys := []dictionary.Hasher{
+		dictionary.StringKey("a"),
+		dictionary.StringKey("z"),
+		dictionary.StringKey("c"),
+	}
+
+	values, found := d.GetMany(keys)
+	require.Equal(t, []interface{}{1, nil, 3}, values, "unexpected values")
+	require.Equal(t, []bool{true, false, true}, found, "unexpected found")
+
+	require.Equal(t, 2, d.DeleteMany(keys), "unexpected number deleted")
+	require.Equal(t, 1, len(d.Keys()), "unexpected number of keys")
+
+	// the table is grown once for all of the items, rather than as it
+	// fills up.
+	o := &countingObserver{calls: make(map[string]int)}
+	d = dictionary.New(dictionary.WithBackend(dictionary.OpenAddressing), dictionary.WithInstrumentation(o))
+	items := make([]dictionary.Item, 1000)
+	for n := range items {
+		items[n] = dictionary.Item{Key: dictionary.Int64Key(n % 900), Value: n}
+	}
+	d.SetMany(items)
+	require.Equal(t, 900, d.Len(), "unexpected length")
+	require.Equal(t, 1, o.calls["rehash"], "should have grown once")
+	v, _ := d.Get(dictionary.Int64Key(5))
+	require.Equal(t, 905, v.(int), "last value should win")
+}
+
+func TestPop(t *testing.T) {
+	d := dictionary.New()
+	k := dictionary.StringKey("foo")
+
+	d.Set(k, "bar")
+	require.Equal(t, "bar", d.Pop(k, "default"), "unexpected value")
+	require.Equal(t, "default", d.Pop(k, "default"), "unexpected value")
+
+	keys := []string{"a", "b", "c", "d"}
+	for _, k := range keys {
+		d.Set(dictionary.StringKey(k), k)
+	}
+
+	for range keys {
+		k, v, ok := d.PopItem()
+		require.Equal(t, true, ok, "should have found item")
+		require.Equal(t, string(k.(dictionary.StringKey)), v.(string), "unexpected value")
+	}
+
+	k2, v, ok := d.PopItem()
+	require.Nil(t, k2)
+	require.Nil(t, v)
+	require.Equal(t, false, ok, "should not have found item")
+}
+
+func TestMerge(t *testing.T) {
+	a := dictionary.New()
+	a.Set(dictionary.StringKey("a"), 1)
+	a.Set(dictionary.StringKey("b"), 2)
+
+	b := dictionary.New()
+	b.Set(dictionary.StringKey("b"), 3)
+	b.Set(dictionary.StringKey("c"), 4)
+
+	a.Merge(b, func(key dictionary.Hasher, x, y interface{}) interface{} {
+		require.Equal(t, dictionary.StringKey("b"), key, "unexpected conflict")
+		return x.(int) + y.(int)
+	})
+
+	for k, val := range map[string]int{"a": 1, "b": 5, "c": 4} {
+		v, ok := a.Get(dictionary.StringKey(k))
+		require.Equal(t, true, ok, "should have found key")
+		require.Equal(t, val, v.(int), "unexpected value")
+	}
+
+	a.Merge(b, nil)
+	v, _ := a.Get(dictionary.StringKey("b"))
+	require.Equal(t, 3, v.(int), "unexpected value")
+}
+
+func keySet(d *dictionary.Dictionary) map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, k := range d.Keys() {
+		v, _ := d.Get(k)
+		m[string(k.(dictionary.StringKey))] = v
+	}
+	return m
+}
+
+func TestSetAlgebra(t *testing.T) {
+	a := dictionary.New()
+	a.Set(dictionary.StringKey("a"), 1)
+	a.Set(dictionary.StringKey("b"), 2)
+
+	b := dictionary.New(dictionary.SetBuckets(7))
+	b.Set(dictionary.StringKey("b"), 3)
+	b.Set(dictionary.StringKey("c"), 4)
+
+	require.Equal(t, map[string]interface{}{"a": 1, "b": 2, "c": 4}, keySet(a.Union(b)))
+	require.Equal(t, map[string]interface{}{"b": 2}, keySet(a.Intersect(b)))
+	require.Equal(t, map[string]interface{}{"a": 1}, keySet(a.Difference(b)))
+	require.Equal(t, map[string]interface{}{"c": 4}, keySet(b.Difference(a)))
+}
+
+func TestDiff(t *testing.T) {
+	a := dictionary.New()
+	a.Set(dictionary.StringKey("a"), 1)
+	a.Set(dictionary.StringKey("b"), 2)
+	a.Set(dictionary.StringKey("c"), 3)
+
+	b := dictionary.New()
+	b.Set(dictionary.StringKey("b"), 2)
+	b.Set(dictionary.StringKey("c"), 4)
+	b.Set(dictionary.StringKey("d"), 5)
+
+	removed, added, changed := a.Diff(b, nil)
+	require.Equal(t, []dictionary.Hasher{dictionary.StringKey("a")}, removed)
+	require.Equal(t, []dictionary.Hasher{dictionary.StringKey("d")}, added)
+	require.Equal(t, []dictionary.Hasher{dictionary.StringKey("c")}, changed)
+
+	_, _, changed = a.Diff(b, func(x, y interface{}) bool { return true })
+	require.Empty(t, changed)
+}
+
+func TestEqual(t *testing.T) {
+	a := dictionary.New()
+	b := dictionary.New(dictionary.SetBuckets(7))
+	require.Equal(t, true, a.Equal(b, nil), "empty dictionaries should be equal")
+
+	for i, k := range []string{"a", "b", "c"} {
+		a.Set(dictionary.StringKey(k), i)
+		b.Set(dictionary.StringKey(k), i)
+	}
+	require.Equal(t, 3, a.Len(), "unexpected length")
+	require.Equal(t, true, a.Equal(b, nil), "dictionaries should be equal")
+
+	b.Set(dictionary.StringKey("c"), 99)
+	require.Equal(t, false, a.Equal(b, nil), "dictionaries should not be equal")
+
+	b.Delete(dictionary.StringKey("c"))
+	require.Equal(t, 2, b.Len(), "unexpected length")
+	require.Equal(t, false, a.Equal(b, nil), "dictionaries should not be equal")
+}
+
+func TestMapConversion(t *testing.T) {
+	m := map[string]interface{}{"a": 1, "b": 2, "c": 3}
+
+	d := dictionary.FromStringMap(m)
+	require.Equal(t, len(m), d.Len(), "unexpected length")
+	require.Equal(t, m, d.ToStringMap())
+
+	g := d.ToMap()
+	require.Equal(t, len(m), len(g), "unexpected length")
+	require.Equal(t, 2, g[dictionary.StringKey("b")], "unexpected value")
+
+	require.Equal(t, true, d.Equal(dictionary.FromMap(g), nil), "dictionaries should be equal")
+}
+
+func TestJSON(t *testing.T) {
+	d := dictionary.FromStringMap(map[string]interface{}{"b": "x", "a": 1.5})
+
+	data, err := json.Marshal(d)
+	require.Nil(t, err)
+	require.Equal(t, `{"a":1.5,"b":"x"}`, string(data))
+
+	var out dictionary.Dictionary
+	require.Nil(t, json.Unmarshal(data, &out))
+	require.Equal(t, true, d.Equal(&out, nil), "dictionaries should be equal")
+
+	i := dictionary.New()
+	i.Set(intKey(1), "one")
+	_, err = json.Marshal(i)
+	require.NotNil(t, err)
+
+	i = dictionary.New(dictionary.SetKeyEncoding(
+		func(k dictionary.Hasher) (string, error) {
+			return strconv.Itoa(int(k.(intKey))), nil
+		},
+		func(s string) (dictionary.Hasher, error) {
+			n, err := strconv.Atoi(s)
+			return intKey(n), err
+		},
+	))
+	i.Set(intKey(1), "one")
+	data, err = json.Marshal(i)
+	require.Nil(t, err)
+	require.Equal(t, `{"1":"one"}`, string(data))
+
+	i.Delete(intKey(1))
+	require.Nil(t, json.Unmarshal(data, i))
+	v, ok := i.Get(intKey(1))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "one", v.(string), "unexpected value")
+}
+
+func TestDecodeJSONObject(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`{"a": {"n": 1}, "b": {"n": 2}} {"c": [1]}`))
+
+	type value struct{ N int }
+	d, err := dictionary.DecodeJSONObject(dec, func() interface{} { return &value{} })
+	require.Nil(t, err)
+	require.Equal(t, 2, d.Len())
+	v, ok := d.Get(dictionary.StringKey("b"))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, &value{N: 2}, v)
+
+	d, err = dictionary.DecodeJSONObject(dec, nil)
+	require.Nil(t, err)
+	v, _ = d.Get(dictionary.StringKey("c"))
+	require.Equal(t, []interface{}{1.0}, v)
+
+	dec = json.NewDecoder(strings.NewReader(`{"1": "one"}`))
+	d, err = dictionary.DecodeJSONObject(dec, nil, dictionary.WithKeyCodec(intCodec{}))
+	require.Nil(t, err)
+	v, _ = d.Get(intKey(1))
+	require.Equal(t, "one", v, "keys should be decoded with the codec")
+
+	_, err = dictionary.DecodeJSONObject(json.NewDecoder(strings.NewReader(`[1]`)), nil)
+	require.NotNil(t, err)
+	_, err = dictionary.DecodeJSONObject(json.NewDecoder(strings.NewReader(`{"a": 1`)), nil)
+	require.NotNil(t, err)
+}
+
+func TestBinary(t *testing.T) {
+	d := dictionary.New(dictionary.SetBuckets(7))
+	for i, k := range []string{"a", "b", "c"} {
+		d.Set(dictionary.StringKey(k), i)
+	}
+
+	data, err := d.MarshalBinary()
+	require.Nil(t, err)
+
+	out := dictionary.New()
+	out.Set(dictionary.StringKey("z"), 26)
+	require.Nil(t, out.UnmarshalBinary(data))
+	require.Equal(t, true, d.Equal(out, nil), "dictionaries should be equal")
+
+	var buf bytes.Buffer
+	require.Nil(t, gob.NewEncoder(&buf).Encode(d))
+
+	var dec dictionary.Dictionary
+	require.Nil(t, gob.NewDecoder(&buf).Decode(&dec))
+	require.Equal(t, true, d.Equal(&dec, nil), "dictionaries should be equal")
+}
+
+// intCodec is a KeyCodec for intKey keys.
+type intCodec struct{}
+
+func (intCodec) EncodeKey(k dictionary.Hasher) ([]byte, error) {
+	return []byte(strconv.Itoa(int(k.(intKey)))), nil
+}
+
+func (intCodec) DecodeKey(b []byte) (dictionary.Hasher, error) {
+	n, err := strconv.Atoi(string(b))
+	return intKey(n), err
+}
+
+func TestKeyCodec(t *testing.T) {
+	codec := dictionary.WithKeyCodec(intCodec{})
+	d := dictionary.New(codec)
+	for n := -3; n < 3; n++ {
+		d.Set(intKey(n), n)
+	}
+
+	data, err := json.Marshal(d)
+	require.Nil(t, err)
+	require.Equal(t, `{"-1":-1,"-2":-2,"-3":-3,"0":0,"1":1,"2":2}`, string(data))
+	out := dictionary.New(codec)
+	require.Nil(t, json.Unmarshal(data, out))
+	v, ok := out.Get(intKey(-3))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, -3.0, v)
+
+	data, err = d.MarshalBinary()
+	require.Nil(t, err)
+	out = dictionary.New(codec)
+	require.Nil(t, out.UnmarshalBinary(data))
+	require.Equal(t, true, d.Equal(out, nil), "dictionaries should be equal")
+	require.Equal(t, dictionary.ErrNoKeyCodec, dictionary.New().UnmarshalBinary(data))
+
+	var buf bytes.Buffer
+	require.Nil(t, d.Encode(&buf))
+	stream := buf.Bytes()
+	out, err = dictionary.Decode(bytes.NewReader(stream), codec)
+	require.Nil(t, err)
+	require.Equal(t, true, d.Equal(out, nil), "dictionaries should be equal")
+	_, err = dictionary.Decode(bytes.NewReader(stream))
+	require.Equal(t, dictionary.ErrNoKeyCodec, err)
+}
+
+func TestStream(t *testing.T) {
+	d := dictionary.New(dictionary.SetBuckets(7))
+	for i := 0; i < 100; i++ {
+		d.Set(dictionary.StringKey(strconv.Itoa(i)), i)
+	}
+
+	var buf bytes.Buffer
+	require.Nil(t, d.Encode(&buf))
+
+	out, err := dictionary.Decode(&buf)
+	require.Nil(t, err)
+	require.Equal(t, true, d.Equal(out, nil), "dictionaries should be equal")
+
+	_, err = dictionary.Decode(bytes.NewBufferString("nope"))
+	require.Equal(t, dictionary.ErrInvalidStream, err)
+
+	// the clock moves on each time it is read, so the entry expires during
+	// Encode. The stream still holds as many entries as its header says.
+	clock := newFakeClock()
+	d = dictionary.New(dictionary.WithClock(func() time.Time {
+		clock.Advance(time.Millisecond)
+		return clock.Now()
+	}))
+	d.Set(dictionary.StringKey("a"), 1)
+	d.SetWithTTL(dictionary.StringKey("b"), 2, 2*time.Millisecond)
+	buf.Reset()
+	require.Nil(t, d.Encode(&buf))
+	_, err = dictionary.Decode(&buf)
+	require.Nil(t, err)
+}
+
+func TestLoadFrom(t *testing.T) {
+	in := `# ports
+http = 80
+https=443
+
+ssh= 22
+`
+	d, err := dictionary.LoadFrom(strings.NewReader(in))
+	require.Nil(t, err)
+	require.Equal(t, 3, d.Len())
+	v, _ := d.Get(dictionary.StringKey("ssh"))
+	require.Equal(t, "22", v)
+
+	var buf bytes.Buffer
+	n, err := d.WriteTo(&buf)
+	require.Nil(t, err)
+	require.Equal(t, "http=80\nhttps=443\nssh=22\n", buf.String())
+	require.Equal(t, int64(buf.Len()), n)
+
+	// a custom separator and value parser.
+	format := dictionary.WithTextFormat(": ", func(s string) (interface{}, error) {
+		return strconv.Atoi(s)
+	}, nil)
+	d, err = dictionary.LoadFrom(strings.NewReader("a: 1\nb: 2\n"), format)
+	require.Nil(t, err)
+	v, _ = d.Get(dictionary.StringKey("b"))
+	require.Equal(t, 2, v)
+	buf.Reset()
+	_, err = d.WriteTo(&buf)
+	require.Nil(t, err)
+	require.Equal(t, "a: 1\nb: 2\n", buf.String())
+
+	_, err = dictionary.LoadFrom(strings.NewReader("a: x\n"), format)
+	require.NotNil(t, err)
+	_, err = dictionary.LoadFrom(strings.NewReader("novalue\n"))
+	require.NotNil(t, err)
+
+	d.Set(dictionary.StringKey("c: d"), 3)
+	_, err = d.WriteTo(&buf)
+	require.NotNil(t, err)
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := os.MkdirTemp("", "dictionary")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	d := dictionary.New()
+	for i := 0; i < 100; i++ {
+		d.Set(dictionary.StringKey(strconv.Itoa(i)), i)
+	}
+
+	path := filepath.Join(dir, "dict")
+	require.Nil(t, d.Save(path))
+
+	out, err := dictionary.Load(path)
+	require.Nil(t, err)
+	require.Equal(t, true, d.Equal(out, nil), "dictionaries should be equal")
+
+	// saving again replaces the file.
+	d.Set(dictionary.StringKey("foo"), "bar")
+	require.Nil(t, d.Save(path))
+	out, err = dictionary.Load(path)
+	require.Nil(t, err)
+	require.Equal(t, true, d.Equal(out, nil), "dictionaries should be equal")
+
+	files, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(files), "temporary file should have been renamed")
+}
+
+func TestEachSorted(t *testing.T) {
+	d := dictionary.New()
+	for _, k := range []string{"c", "a", "d", "b"} {
+		d.Set(dictionary.StringKey(k), k)
+	}
+
+	less := func(a, b dictionary.Hasher) bool {
+		return a.(dictionary.StringKey) < b.(dictionary.StringKey)
+	}
+
+	var seen []string
+	err := d.EachSorted(less, func(h dictionary.Hasher, v interface{}) error {
+		seen = append(seen, v.(string))
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"a", "b", "c", "d"}, seen)
+
+	keys := d.SortedKeys(less)
+	require.Equal(t, []dictionary.Hasher{
+		dictionary.StringKey("a"),
+		dictionary.StringKey("b"),
+		dictionary.StringKey("c"),
+		dictionary.StringKey("d"),
+	}, keys)
+}
+
+func TestOrdered(t *testing.T) {
+	d := dictionary.New()
+
+	_, _, ok := d.Min()
+	require.Equal(t, false, ok, "empty dictionary has no minimum")
+
+	for _, k := range []string{"c", "a", "d", "b"} {
+		d.Set(dictionary.StringKey(k), k)
+	}
+
+	require.Equal(t, d.SortedKeys(dictionary.OrderedLess), d.SortedKeys(nil))
+	require.Equal(t, dictionary.StringKey("a"), d.SortedKeys(nil)[0])
+
+	k, v, ok := d.Min()
+	require.Equal(t, true, ok, "should have found minimum")
+	require.Equal(t, dictionary.StringKey("a"), k)
+	require.Equal(t, "a", v.(string))
+
+	k, _, _ = d.Max()
+	require.Equal(t, dictionary.StringKey("d"), k)
+
+	// the remembered entries follow changes.
+	d.Set(dictionary.StringKey("0"), "0")
+	d.Delete(dictionary.StringKey("d"))
+	k, _, _ = d.Min()
+	require.Equal(t, dictionary.StringKey("0"), k)
+	k, _, _ = d.Max()
+	require.Equal(t, dictionary.StringKey("c"), k)
+	d.Set(dictionary.StringKey("c"), "C")
+	_, v, _ = d.Max()
+	require.Equal(t, "C", v.(string))
+	d.Clear()
+	_, _, ok = d.Max()
+	require.Equal(t, false, ok, "empty dictionary has no maximum")
+
+	// keys that are not Ordered, or cannot be compared with the remembered
+	// ones, may still be added, and are only a problem if they are there
+	// when Min scans.
+	d.Set(dictionary.Int64Key(2), "2")
+	d.Set(dictionary.Int64Key(1), "1")
+	k, _, _ = d.Min()
+	require.Equal(t, dictionary.Int64Key(1), k)
+	d.Set(dictionary.StringKey("a"), "a")
+	d.Set(d.CachedKey(dictionary.StringKey("b")), "b")
+	d.Delete(dictionary.StringKey("a"))
+	d.Delete(d.CachedKey(dictionary.StringKey("b")))
+	k, _, _ = d.Min()
+	require.Equal(t, dictionary.Int64Key(1), k)
+	k, _, _ = d.Max()
+	require.Equal(t, dictionary.Int64Key(2), k)
+}
+
+func TestDefault(t *testing.T) {
+	d := dictionary.NewDefault(func(k dictionary.Hasher) interface{} {
+		return []string{}
+	})
+
+	for _, w := range []string{"apple", "avocado", "banana"} {
+		k := dictionary.StringKey(w[:1])
+		v, ok := d.Get(k)
+		require.Equal(t, true, ok, "should always find key")
+		d.Set(k, append(v.([]string), w))
+	}
+
+	require.Equal(t, false, d.Contains(dictionary.StringKey("c")), "should not create entries")
+
+	v, _ := d.Get(dictionary.StringKey("a"))
+	require.Equal(t, []string{"apple", "avocado"}, v)
+	v, _ = d.Get(dictionary.StringKey("c"))
+	require.Equal(t, []string{}, v)
+	require.Equal(t, 3, d.Len(), "unexpected length")
+}
+
+func TestPath(t *testing.T) {
+	d := dictionary.New()
+
+	require.Nil(t, d.SetDotted("server.http.port", 8080))
+	require.Nil(t, d.SetDotted("server.http.host", "localhost"))
+	require.Nil(t, d.SetPath([]dictionary.Hasher{dictionary.StringKey("server"), intKey(1)}, "one"))
+
+	v, ok := d.GetDotted("server.http.port")
+	require.Equal(t, true, ok, "should have found path")
+	require.Equal(t, 8080, v.(int), "unexpected value")
+
+	v, ok = d.GetPath([]dictionary.Hasher{dictionary.StringKey("server"), intKey(1)})
+	require.Equal(t, true, ok, "should have found path")
+	require.Equal(t, "one", v.(string), "unexpected value")
+
+	v, ok = d.GetDotted("server.http")
+	require.Equal(t, true, ok, "should have found path")
+	require.Equal(t, 2, v.(*dictionary.Dictionary).Len(), "unexpected length")
+
+	_, ok = d.GetDotted("server.http.port.number")
+	require.Equal(t, false, ok, "should not have found path")
+	_, ok = d.GetDotted("server.https")
+	require.Equal(t, false, ok, "should not have found path")
+
+	require.Equal(t, dictionary.ErrNotDictionary, d.SetDotted("server.http.port.number", 1))
+	require.Equal(t, dictionary.ErrEmptyPath, d.SetPath(nil, 1))
+}
+
+func TestSeeds(t *testing.T) {
+	a := dictionary.New()
+	b := dictionary.New()
+	for i := 0; i < 100; i++ {
+		k := dictionary.StringKey(strconv.Itoa(i))
+		a.Set(k, i)
+		b.Set(k, i)
+	}
+
+	// the dictionaries have different seeds, so must rehash keys when
+	// comparing.
+	require.Equal(t, true, a.Equal(b, nil), "dictionaries should be equal")
+	require.Equal(t, 100, a.Union(b).Len(), "unexpected length")
+	require.Equal(t, 100, a.Intersect(b).Len(), "unexpected length")
+
+	seed := maphash.MakeSeed()
+	c := dictionary.New(dictionary.SetHashSeed(seed))
+	d := dictionary.New(dictionary.SetHashSeed(seed))
+	for i := 0; i < 100; i++ {
+		k := dictionary.StringKey(strconv.Itoa(i))
+		c.Set(k, i)
+		d.Set(k, i)
+	}
+	require.Equal(t, c.Keys(), d.Keys(), "same seed should give the same layout")
+}
+
+func TestStringHash(t *testing.T) {
+	require.Equal(t, uint64(0xcbf29ce484222325), dictionary.FNV1a(""))
+	require.Equal(t, uint64(0xaf63dc4c8601ec8c), dictionary.FNV1a("a"))
+	require.Equal(t, uint64(dictionary.StringKey("foo").Hash()), dictionary.CRC32("foo"))
+
+	for _, f := range []dictionary.StringHashFunc{dictionary.FNV1a, dictionary.CRC32} {
+		d := dictionary.New(dictionary.WithStringHash(f))
+		e := dictionary.New()
+		for i := 0; i < 100; i++ {
+			k := dictionary.StringKey(strconv.Itoa(i))
+			d.Set(k, i)
+			e.Set(k, i)
+		}
+		v, ok := d.Get(dictionary.StringKey("42"))
+		require.Equal(t, true, ok, "should have found key")
+		require.Equal(t, 42, v.(int), "unexpected value")
+		require.Equal(t, true, d.Equal(e, nil), "dictionaries should be equal")
+		require.Equal(t, true, d.Equal(d.Intersect(e), nil), "dictionaries should be equal")
+	}
+}
+
+func TestBytesKey(t *testing.T) {
+	d := dictionary.New()
+
+	id := []byte{0xde, 0xad, 0xbe, 0xef}
+	d.Set(dictionary.BytesKey(id), "beef")
+
+	v, ok := d.Get(dictionary.BytesKey([]byte{0xde, 0xad, 0xbe, 0xef}))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "beef", v.(string), "unexpected value")
+
+	_, ok = d.Get(dictionary.BytesKey([]byte{0xde, 0xad}))
+	require.Equal(t, false, ok, "should not have found key")
+
+	d.Set(dictionary.BytesKey([]byte{0x01}), "one")
+	k, _, _ := d.Min()
+	require.Equal(t, dictionary.BytesKey([]byte{0x01}), k)
+
+	data, err := d.MarshalBinary()
+	require.Nil(t, err)
+	out := dictionary.New()
+	require.Nil(t, out.UnmarshalBinary(data))
+	require.Equal(t, true, d.Equal(out, nil), "dictionaries should be equal")
+}
+
+func TestItemPool(t *testing.T) {
+	d := dictionary.New(dictionary.WithItemPool(true), dictionary.SetMaxEntries(50))
+
+	for round := 0; round < 10; round++ {
+		for n := 0; n < 100; n++ {
+			d.Set(intKey(n), round*n)
+		}
+		for n := 50; n < 100; n++ {
+			v, ok := d.Get(intKey(n))
+			require.Equal(t, true, ok, "should have found key")
+			require.Equal(t, round*n, v.(int), "unexpected value")
+		}
+		for n := 50; n < 75; n++ {
+			v, ok := d.Delete(intKey(n))
+			require.Equal(t, true, ok, "should have deleted key")
+			require.Equal(t, round*n, v.(int), "unexpected value")
+		}
+		k, v, ok := d.PopItem()
+		require.Equal(t, true, ok, "should have popped an entry")
+		require.Equal(t, int(k.(intKey))*round, v.(int), "unexpected value")
+		require.Equal(t, 24, d.Len(), "unexpected length")
+	}
+}
+
+func TestArena(t *testing.T) {
+	d := dictionary.New(dictionary.WithArena(16), dictionary.SetMaxEntries(50))
+
+	for round := 0; round < 10; round++ {
+		for n := 0; n < 100; n++ {
+			d.Set(intKey(n), round*n)
+		}
+		for n := 50; n < 75; n++ {
+			v, ok := d.Delete(intKey(n))
+			require.Equal(t, true, ok, "should have deleted key")
+			require.Equal(t, round*n, v.(int), "unexpected value")
+		}
+		for n := 75; n < 100; n++ {
+			v, ok := d.Get(intKey(n))
+			require.Equal(t, true, ok, "should have found key")
+			require.Equal(t, round*n, v.(int), "unexpected value")
+		}
+		require.Equal(t, 25, d.Len(), "unexpected length")
+	}
+	require.NoError(t, d.CheckInvariants())
+
+	d.Clear()
+	require.Equal(t, 0, d.Len(), "unexpected length")
+	d.Set(intKey(1), 1)
+	v, _ := d.Get(intKey(1))
+	require.Equal(t, 1, v.(int), "unexpected value")
+}
+
+func TestStats(t *testing.T) {
+	d := dictionary.New(dictionary.SetBuckets(10))
+	for n := 0; n < 30; n++ {
+		d.Set(intKey(n), n)
+	}
+
+	require.Equal(t, dictionary.Stats{
+		Entries:    30,
+		Buckets:    10,
+		LoadFactor: 3,
+		MaxChain:   3,
+		MeanChain:  3,
+		Histogram:  []int{0, 0, 0, 10},
+	}, d.Stats())
+
+	// with the other backends, each entry has a slot of its own.
+	for name, b := range backends {
+		if b == dictionary.Chaining {
+			continue
+		}
+		d := dictionary.New(dictionary.WithBackend(b))
+		for n := 0; n < 1000; n++ {
+			d.Set(dictionary.Int64Key(n), n)
+		}
+		s := d.Stats()
+		require.Equal(t, 1000, s.Entries, name)
+		require.Equal(t, s.Buckets-s.EmptyBuckets, s.Entries, name)
+		require.True(t, s.MeanChain >= 1 && s.MeanChain <= 4, "%s: unexpected mean chain %v", name, s.MeanChain)
+	}
+}
+
+func TestDump(t *testing.T) {
+	d := dictionary.New(dictionary.SetBuckets(3))
+	for n := 0; n < 6; n++ {
+		d.Set(intKey(n), n)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, d.Dump(&buf))
+	require.Equal(t, `bucket 0: 0 (0x0) -> 3 (0x3)
+bucket 1: 1 (0x1) -> 4 (0x4)
+bucket 2: 2 (0x2) -> 5 (0x5)
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, d.DOT(&buf))
+	require.Contains(t, buf.String(), `buckets [label="<b0> 0|<b1> 1|<b2> 2"];`)
+	require.Contains(t, buf.String(), "\tbuckets:b1 -> item2;\n")
+	require.Contains(t, buf.String(), "\titem3 [label=\"4|0x4\"];\n\titem2 -> item3;\n")
+}
+
+func TestString(t *testing.T) {
+	d := dictionary.New()
+	require.Equal(t, "{}", d.String())
+
+	for _, k := range []string{"c", "a", "b"} {
+		d.Set(dictionary.StringKey(k), len(k))
+	}
+	require.Equal(t, "{a: 1, b: 1, c: 1}", d.String())
+	require.Equal(t, `dictionary.Dictionary{"a": 1, "b": 1, "c": 1}`, fmt.Sprintf("%#v", d))
+
+	for n := 0; n < 200; n++ {
+		d.Set(dictionary.StringKey(fmt.Sprintf("k%03d", n)), n)
+	}
+	require.Equal(t, true, strings.HasSuffix(d.String(), "k096: 96, ... 103 more}"), "should be cut short")
+
+	// formatting leaves expired entries in place, and does not call hooks.
+	deleted := 0
+	d = dictionary.New(dictionary.WithOnDelete(func(dictionary.Hasher, interface{}) {
+		deleted++
+	}))
+	d.Set(dictionary.StringKey("a"), 1)
+	d.SetWithTTL(dictionary.StringKey("b"), 2, -time.Second)
+	require.Equal(t, "{a: 1}", d.String())
+	require.Equal(t, 0, deleted, "formatting should not remove entries")
+
+	// keys of different types are not compared with each other.
+	d.Set(dictionary.Int64Key(1), 1)
+	require.Equal(t, 2, strings.Count(d.String(), ":"), "unexpected entries")
+}
+
+// mutableKey hashes to its current value, so changing it after it has been
+// added breaks the dictionary.
+type mutableKey struct {
+	n *int
+}
+
+func (k mutableKey) Hash() uint32 {
+	return uint32(*k.n)
+}
+
+func (k mutableKey) Equal(v interface{}) bool {
+	return k.n == v.(mutableKey).n
+}
+
+func TestCheckInvariants(t *testing.T) {
+	d := dictionary.New()
+	require.NoError(t, d.CheckInvariants())
+
+	n := 1
+	d.Set(mutableKey{&n}, "a")
+	require.NoError(t, d.CheckInvariants())
+
+	n = 2
+	require.Error(t, d.CheckInvariants())
+}
+
+func TestExpvar(t *testing.T) {
+	d := dictionary.New()
+	d.Set(dictionary.StringKey("a"), 1)
+	d.Get(dictionary.StringKey("a"))
+	d.Get(dictionary.StringKey("b"))
+	d.Contains(dictionary.StringKey("c"))
+
+	var s dictionary.Stats
+	require.NoError(t, json.Unmarshal([]byte(d.Var().String()), &s))
+	require.Equal(t, 1, s.Entries, "unexpected entries")
+	require.Equal(t, uint64(1), s.Hits, "unexpected hits")
+	require.Equal(t, uint64(2), s.Misses, "unexpected misses")
+
+	// published names must be unique, even when the test is run again with
+	// -count. The published var keeps d alive, so its address is not reused.
+	name := fmt.Sprintf("test-dictionary-%p", d)
+	d.PublishExpvar(name)
+	require.Equal(t, d.Var().String(), expvar.Get(name).String())
+}
+
+// countingObserver counts the calls to each of its methods.
+type countingObserver struct {
+	calls map[string]int
+}
+
+func (o *countingObserver) Hit(dictionary.Hasher)    { o.calls["hit"]++ }
+func (o *countingObserver) Miss(dictionary.Hasher)   { o.calls["miss"]++ }
+func (o *countingObserver) Set(dictionary.Hasher)    { o.calls["set"]++ }
+func (o *countingObserver) Delete(dictionary.Hasher) { o.calls["delete"]++ }
+func (o *countingObserver) Evict(dictionary.Hasher)  { o.calls["evict"]++ }
+func (o *countingObserver) Expire(dictionary.Hasher) { o.calls["expire"]++ }
+func (o *countingObserver) Rehash(int)               { o.calls["rehash"]++ }
+
+func TestInstrumentation(t *testing.T) {
+	o := &countingObserver{calls: make(map[string]int)}
+	clock := newFakeClock()
+	d := dictionary.New(dictionary.WithInstrumentation(o), dictionary.SetMaxEntries(3), dictionary.WithClock(clock.Now))
+
+	for n := 0; n < 5; n++ {
+		d.Set(intKey(n), n)
+	}
+	d.Set(intKey(4), 4)
+	d.Get(intKey(4))
+	d.Get(intKey(0))
+	d.Contains(intKey(3))
+	d.Delete(intKey(3))
+	d.SetWithTTL(intKey(5), 5, time.Millisecond)
+	clock.Advance(time.Millisecond)
+	d.Get(intKey(5))
+	d.Rehash(7)
+
+	require.Equal(t, map[string]int{
+		"set":    7,
+		"hit":    2,
+		"miss":   2,
+		"delete": 1,
+		"evict":  2,
+		"expire": 1,
+		"rehash": 1,
+	}, o.calls)
+}
+
+func TestHooks(t *testing.T) {
+	// keep an index from value to key in sync.
+	index := make(map[int]intKey)
+	d := dictionary.New(
+		dictionary.SetMaxEntries(3),
+		dictionary.WithOnInsert(func(k dictionary.Hasher, v interface{}) {
+			index[v.(int)] = k.(intKey)
+		}),
+		dictionary.WithOnReplace(func(k dictionary.Hasher, old, new interface{}) {
+			delete(index, old.(int))
+			index[new.(int)] = k.(intKey)
+		}),
+		dictionary.WithOnDelete(func(k dictionary.Hasher, v interface{}) {
+			delete(index, v.(int))
+		}),
+	)
+
+	d.Set(intKey(1), 10)
+	d.Set(intKey(2), 20)
+	d.Set(intKey(1), 11)
+	require.Equal(t, map[int]intKey{11: 1, 20: 2}, index)
+
+	d.Delete(intKey(2))
+	require.Equal(t, map[int]intKey{11: 1}, index)
+
+	// evicted entries are removed from the index too.
+	for n := 3; n < 6; n++ {
+		d.Set(intKey(n), n*10)
+	}
+	require.Equal(t, map[int]intKey{30: 3, 40: 4, 50: 5}, index)
+}
+
+func TestWatch(t *testing.T) {
+	d := dictionary.New()
+	a, cancelA := d.Watch(dictionary.StringKey("a"))
+	all, cancelAll := d.Watch(nil)
+	defer cancelAll()
+
+	d.Set(dictionary.StringKey("a"), 1)
+	d.Set(dictionary.StringKey("b"), 2)
+	d.Set(dictionary.StringKey("a"), 3)
+	d.Delete(dictionary.StringKey("a"))
+
+	for _, want := range []dictionary.Event{
+		{Type: dictionary.EventSet, Key: dictionary.StringKey("a"), Value: 1},
+		{Type: dictionary.EventSet, Key: dictionary.StringKey("a"), Value: 3},
+		{Type: dictionary.EventDelete, Key: dictionary.StringKey("a"), Value: 3},
+	} {
+		require.Equal(t, want, <-a)
+	}
+
+	for _, want := range []interface{}{1, 2, 3, 3} {
+		require.Equal(t, want, (<-all).Value)
+	}
+
+	cancelA()
+	_, ok := <-a
+	require.Equal(t, false, ok, "channel should be closed")
+	d.Set(dictionary.StringKey("a"), 4)
+	require.Equal(t, 4, (<-all).Value)
+
+	// keys of other types are not compared with the watched key.
+	w, cancelW := d.Watch(dictionary.StringKey("a"))
+	defer cancelW()
+	d.Set(dictionary.Int64Key(1), 5)
+	d.Set(dictionary.StringKey("a"), 6)
+	require.Equal(t, 6, (<-w).Value)
+}
+
+func TestGetWait(t *testing.T) {
+	d := dictionary.New()
+	d.Set(dictionary.StringKey("a"), 1)
+
+	d.Lock()
+	defer d.Unlock()
+	v, err := d.GetWait(context.Background(), dictionary.StringKey("a"))
+	require.NoError(t, err)
+	require.Equal(t, 1, v.(int), "unexpected value")
+
+	go func() {
+		d.Lock()
+		defer d.Unlock()
+		d.Set(dictionary.StringKey("b"), 2)
+	}()
+	v, err = d.GetWait(context.Background(), dictionary.StringKey("b"))
+	require.NoError(t, err)
+	require.Equal(t, 2, v.(int), "unexpected value")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = d.GetWait(ctx, dictionary.StringKey("c"))
+	require.Equal(t, context.DeadlineExceeded, err)
+	// the lock is held again, so the deferred Unlock does not panic.
+	d.Set(dictionary.StringKey("c"), 3)
+}
+
+func TestAdd(t *testing.T) {
+	d := dictionary.New()
+	require.Equal(t, int64(5), d.Add(dictionary.StringKey("a"), 5))
+	require.Equal(t, int64(3), d.Add(dictionary.StringKey("a"), -2))
+	require.Equal(t, 1.5, d.AddFloat(dictionary.StringKey("b"), 1.5))
+	require.Equal(t, 1.75, d.AddFloat(dictionary.StringKey("b"), 0.25))
+
+	d.Set(dictionary.StringKey("c"), "not a number")
+	require.Panics(t, func() { d.Add(dictionary.StringKey("c"), 1) })
+
+	// a is used far more often than b, so b is turned away.
+	d = dictionary.New(dictionary.SetMaxEntries(1), dictionary.WithTinyLFU())
+	for n := 0; n < 5; n++ {
+		d.Add(dictionary.StringKey("a"), 1)
+	}
+	require.Equal(t, int64(0), d.Add(dictionary.StringKey("b"), 1))
+	require.Equal(t, 0.0, d.AddFloat(dictionary.StringKey("b"), 1))
+	require.Equal(t, false, d.Contains(dictionary.StringKey("b")), "should not have found key")
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	d := dictionary.New()
+	k := dictionary.StringKey("a")
+
+	require.Equal(t, false, d.CompareAndSwap(k, nil, 1, nil), "should not swap a missing key")
+	d.Set(k, 1)
+	require.Equal(t, false, d.CompareAndSwap(k, 2, 3, nil), "should not swap a different value")
+	require.Equal(t, true, d.CompareAndSwap(k, 1, 3, nil), "should have swapped")
+
+	v, _ := d.Get(k)
+	require.Equal(t, 3, v.(int), "unexpected value")
+
+	require.Equal(t, false, d.CompareAndDelete(k, 1, nil), "should not delete a different value")
+	odd := func(a, b interface{}) bool { return a.(int)%2 == b.(int)%2 }
+	require.Equal(t, true, d.CompareAndDelete(k, 1, odd), "should have deleted")
+	require.Equal(t, false, d.Contains(k), "should not have found key")
+}
+
+func TestSwap(t *testing.T) {
+	d := dictionary.New()
+	k := dictionary.StringKey("a")
+
+	prev, ok := d.Swap(k, 1)
+	require.Equal(t, false, ok, "should not have existed")
+	require.Nil(t, prev)
+
+	prev, ok = d.Swap(k, 2)
+	require.Equal(t, true, ok, "should have existed")
+	require.Equal(t, 1, prev.(int), "unexpected previous value")
+
+	v, _ := d.Get(k)
+	require.Equal(t, 2, v.(int), "unexpected value")
+}
+
+func TestReadOnly(t *testing.T) {
+	d := dictionary.NewDefault(func(dictionary.Hasher) interface{} { return 0 })
+	d.Set(dictionary.StringKey("a"), 1)
+
+	v := d.ReadOnly()
+	val, ok := v.Get(dictionary.StringKey("a"))
+	require.Equal(t, true, ok, "key should be present")
+	require.Equal(t, 1, val.(int), "unexpected value")
+
+	_, ok = v.Get(dictionary.StringKey("b"))
+	require.Equal(t, false, ok, "view should not create missing keys")
+	require.Equal(t, 1, v.Len(), "unexpected length")
+
+	d.Set(dictionary.StringKey("c"), 3)
+	require.Equal(t, true, v.Contains(dictionary.StringKey("c")), "view should see changes")
+	require.Len(t, v.Keys(), 2)
+}
+
+func TestSnapshot(t *testing.T) {
+	d := dictionary.New()
+	for n := 0; n < 100; n++ {
+		d.Set(dictionary.Int64Key(n), n)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 100; n < 1000; n++ {
+			d.Lock()
+			d.Set(dictionary.Int64Key(n), n)
+			d.Delete(dictionary.Int64Key(n - 100))
+			d.Unlock()
+		}
+	}()
+
+	d.Lock()
+	s := d.Snapshot()
+	d.Unlock()
+	require.Equal(t, 100, s.Len(), "unexpected length")
+	err := s.Each(func(k dictionary.Hasher, v interface{}) error {
+		require.Equal(t, int(k.(dictionary.Int64Key)), v.(int), "unexpected value")
+		return nil
+	})
+	require.Nil(t, err)
+	<-done
+}
+
+func TestTxn(t *testing.T) {
+	d := dictionary.New()
+	d.Set(dictionary.StringKey("a"), 1)
+
+	err := d.Txn(func(tx *dictionary.Txn) error {
+		tx.Set(dictionary.StringKey("b"), 2)
+		tx.Delete(dictionary.StringKey("a"))
+		_, ok := tx.Get(dictionary.StringKey("a"))
+		require.Equal(t, false, ok, "transaction should see its own delete")
+		require.Equal(t, true, d.Contains(dictionary.StringKey("a")), "changes should not be applied yet")
+		return errors.New("abort")
+	})
+	require.EqualError(t, err, "abort")
+	require.Equal(t, true, d.Contains(dictionary.StringKey("a")), "aborted changes should not be applied")
+	require.Equal(t, false, d.Contains(dictionary.StringKey("b")), "aborted changes should not be applied")
+
+	err = d.Txn(func(tx *dictionary.Txn) error {
+		v, ok := tx.Get(dictionary.StringKey("a"))
+		require.Equal(t, true, ok, "key should be present")
+		tx.Set(dictionary.StringKey("b"), v.(int)+1)
+		tx.Delete(dictionary.StringKey("a"))
+		v, _ = tx.Get(dictionary.StringKey("b"))
+		require.Equal(t, 2, v.(int), "transaction should see its own set")
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, false, d.Contains(dictionary.StringKey("a")), "key should have been deleted")
+	v, _ := d.Get(dictionary.StringKey("b"))
+	require.Equal(t, 2, v.(int), "unexpected value")
+
+	// pending changes are keyed the way the dictionary is, and the caller
+	// may hold the lock.
+	n := dictionary.New(dictionary.WithKeyNormalizer(func(k dictionary.Hasher) dictionary.Hasher {
+		return dictionary.StringKey(strings.ToLower(string(k.(dictionary.StringKey))))
+	}))
+	n.Lock()
+	defer n.Unlock()
+	err = n.Txn(func(tx *dictionary.Txn) error {
+		tx.Set(dictionary.StringKey("Foo"), 1)
+		v, ok := tx.Get(dictionary.StringKey("FOO"))
+		require.Equal(t, true, ok, "transaction should see its own set")
+		require.Equal(t, 1, v.(int), "unexpected value")
+		tx.Delete(dictionary.StringKey("foo"))
+		_, ok = tx.Get(dictionary.StringKey("Foo"))
+		require.Equal(t, false, ok, "transaction should see its own delete")
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 0, n.Len(), "unexpected length")
+}
+
+func TestKeysWithPrefix(t *testing.T) {
+	for name, options := range map[string][]dictionary.OptionsFunc{
+		"scan":  nil,
+		"index": {dictionary.WithPrefixIndex()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := dictionary.New(options...)
+			for _, k := range []string{"user:2", "group:1", "user:1", "user", "users:1"} {
+				d.Set(dictionary.StringKey(k), k)
+			}
+			d.Set(dictionary.Int64Key(1), "not a string")
+			d.Delete(dictionary.StringKey("user:2"))
+
+			keys := d.KeysWithPrefix("user:")
+			require.Equal(t, []dictionary.Hasher{dictionary.StringKey("user:1")}, keys)
+			require.Len(t, d.KeysWithPrefix("user"), 3)
+			require.Len(t, d.KeysWithPrefix(""), 4)
+
+			seen := 0
+			require.Nil(t, d.EachWithPrefix("group", func(k dictionary.Hasher, v interface{}) error {
+				seen++
+				require.Equal(t, "group:1", v.(string), "unexpected value")
+				return nil
+			}))
+			require.Equal(t, 1, seen, "unexpected number of entries")
+
+			// entries removed by f, even if another is added in their
+			// place, are not passed to f.
+			seen = 0
+			require.Nil(t, d.EachWithPrefix("user", func(k dictionary.Hasher, v interface{}) error {
+				seen++
+				for _, k := range []string{"user", "user:1", "users:1"} {
+					d.Delete(dictionary.StringKey(k))
+				}
+				d.Set(dictionary.StringKey("other"), "other")
+				return nil
+			}))
+			require.Equal(t, 1, seen, "unexpected number of entries")
+		})
+	}
+}
+
+func TestRandomKey(t *testing.T) {
+	for name, options := range map[string][]dictionary.OptionsFunc{
+		"scan":  nil,
+		"index": {dictionary.WithRandomAccess()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := dictionary.New(options...)
+			_, ok := d.RandomKey()
+			require.Equal(t, false, ok, "empty dictionary should have no key")
+
+			for n := 0; n < 4; n++ {
+				d.Set(dictionary.Int64Key(n), n)
+			}
+			counts := make(map[dictionary.Hasher]int)
+			for n := 0; n < 4000; n++ {
+				k, ok := d.RandomKey()
+				require.Equal(t, true, ok, "should have picked a key")
+				counts[k]++
+			}
+			require.Len(t, counts, 4)
+			for k, c := range counts {
+				require.InDelta(t, 1000, c, 200, "key %v picked too rarely or too often", k)
+			}
+
+			seen := make(map[dictionary.Hasher]bool)
+			for n := 0; n < 4; n++ {
+				k, v, ok := d.PopRandom()
+				require.Equal(t, true, ok, "should have popped an entry")
+				require.Equal(t, int(k.(dictionary.Int64Key)), v.(int), "unexpected value")
+				seen[k] = true
+			}
+			require.Len(t, seen, 4)
+			require.Equal(t, 0, d.Len(), "unexpected length")
+			_, _, ok = d.PopRandom()
+			require.Equal(t, false, ok, "empty dictionary should have no entry")
+		})
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	// many more keys than expected, so the filter is rebuilt as they are
+	// added and removed.
+	d := dictionary.New(dictionary.WithBloomFilter(100, 0.01))
+	for n := 0; n < 1000; n++ {
+		d.Set(dictionary.Int64Key(n), n)
+	}
+	for n := 0; n < 1000; n += 2 {
+		d.Delete(dictionary.Int64Key(n))
+	}
+	for n := 0; n < 2000; n++ {
+		require.Equal(t, n < 1000 && n%2 == 1, d.Contains(dictionary.Int64Key(n)), "unexpected key %d", n)
+	}
+	v, ok := d.Get(dictionary.Int64Key(999))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, 999, v.(int), "unexpected value")
+
+	d.Clear()
+	require.Equal(t, false, d.Contains(dictionary.Int64Key(1)), "should not have found key")
+	d.Set(dictionary.Int64Key(1), 1)
+	require.Equal(t, true, d.Contains(dictionary.Int64Key(1)), "should have found key")
+
+	_, err := dictionary.NewWithError(dictionary.WithBloomFilter(100, 1.5))
+	require.ErrorIs(t, err, dictionary.ErrInvalidOption)
+}
+
+func TestSample(t *testing.T) {
+	d := dictionary.New()
+	for n := 0; n < 10; n++ {
+		d.Set(dictionary.Int64Key(n), n)
+	}
+
+	require.Len(t, d.Sample(20), 10)
+	require.Len(t, d.Sample(0), 0)
+
+	counts := make(map[dictionary.Hasher]int)
+	for n := 0; n < 1000; n++ {
+		items := d.Sample(3)
+		require.Len(t, items, 3)
+		for _, i := range items {
+			require.Equal(t, int(i.Key.(dictionary.Int64Key)), i.Value.(int), "unexpected value")
+			counts[i.Key]++
+		}
+	}
+	// each key should be picked about 300 times.
+	require.Len(t, counts, 10)
+	for k, c := range counts {
+		require.InDelta(t, 300, c, 100, "key %v picked too rarely or too often", k)
+	}
+}
+
+func TestNewWithError(t *testing.T) {
+	d, err := dictionary.NewWithError(dictionary.SetBuckets(7))
+	require.Nil(t, err)
+	d.Set(dictionary.StringKey("a"), 1)
+	require.Equal(t, 1, d.Len(), "unexpected length")
+
+	_, err = dictionary.NewWithError(dictionary.SetBuckets(0))
+	require.Equal(t, dictionary.ErrInvalidBucketCount, err)
+
+	for _, option := range []dictionary.OptionsFunc{
+		dictionary.WithCapacity(-1),
+		dictionary.SetMaxEntries(-1),
+		dictionary.SetMaxWeight(10),
+		dictionary.WithAutoShrink(2),
+		dictionary.WithBackend(dictionary.Backend(100)),
+		dictionary.WithJanitor(-time.Second),
+	} {
+		_, err = dictionary.NewWithError(option)
+		require.True(t, errors.Is(err, dictionary.ErrInvalidOption), "unexpected error %v", err)
+	}
+
+	require.Panics(t, func() { dictionary.MustNew(dictionary.SetBuckets(0)) })
+	require.PanicsWithValue(t, dictionary.ErrNilKey, func() { d.Set(nil, 1) })
+
+	// New uses the default instead.
+	d = dictionary.New(dictionary.SetBuckets(0))
+	d.Set(dictionary.StringKey("a"), 1)
+	require.Equal(t, 1, d.Len(), "unexpected length")
+}
+
+func TestEntry(t *testing.T) {
+	d := dictionary.New()
+	k := dictionary.StringKey("a")
+
+	e := d.Entry(k)
+	require.Equal(t, false, e.Exists(), "key should not be present")
+	require.Equal(t, 1, e.OrInsert(1).(int), "unexpected value")
+	require.Equal(t, 1, e.OrInsert(2).(int), "existing value should be kept")
+	require.Equal(t, 1, e.OrInsertWith(func() interface{} {
+		t.Fatal("should not be called for a present key")
+		return nil
+	}).(int), "unexpected value")
+
+	e = d.Entry(k)
+	v, ok := e.Value()
+	require.Equal(t, true, ok, "key should be present")
+	e.SetValue(v.(int) + 1)
+	v, _ = d.Get(k)
+	require.Equal(t, 2, v.(int), "unexpected value")
+
+	require.Equal(t, true, e.Delete(), "should have deleted key")
+	require.Equal(t, false, e.Delete(), "key should already be deleted")
+	require.Equal(t, 0, d.Len(), "unexpected length")
+
+	e.SetValue(3)
+	v, _ = d.Get(k)
+	require.Equal(t, 3, v.(int), "unexpected value")
+}
+
+func TestIterator(t *testing.T) {
+	d := dictionary.New()
+
+	keys := []string{"a", "b", "c", "d"}
+	for _, k := range keys {
+		d.Set(dictionary.StringKey(k), k)
+	}
+
+	it := d.Iterator()
+	for pass := 0; pass < 2; pass++ {
+		seen := make(map[string]bool, len(keys))
+		for it.Next() {
+			k := string(it.Key().(dictionary.StringKey))
+			require.Equal(t, k, it.Value().(string), "unexpected value")
+			seen[k] = true
+		}
+		require.Equal(t, len(keys), len(seen), "unexpected number of entries")
+		require.Equal(t, false, it.Next(), "exhausted iterator should stay exhausted")
+		it.Reset()
+	}
+
+	// a zero Dictionary, such as one about to be unmarshaled into, has no
+	// entries.
+	var z dictionary.Dictionary
+	it = z.Iterator()
+	require.Equal(t, false, it.Next(), "zero dictionary should have no entries")
+	it.Reset()
+	require.Equal(t, false, it.Next(), "zero dictionary should have no entries")
+	require.Equal(t, 0, z.Stats().Entries, "unexpected number of entries")
+}
+
 func ExampleNew() {
 	d := dictionary.New()
 	k := dictionary.StringKey("foo")
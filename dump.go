@@ -0,0 +1,70 @@
+package dictionary
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Dump writes the layout of the buckets to w, one line per bucket, listing
+// the key and hash of each entry in the bucket. It is meant for seeing how
+// keys are spread across the buckets and where they collide. For backends
+// other than Chaining, each slot of the table is a bucket.
+func (d *Dictionary) Dump(w io.Writer) error {
+	d.removeExpired()
+
+	b := bufio.NewWriter(w)
+	n := 0
+	d.store.layout(func(items []*item) {
+		fmt.Fprintf(b, "bucket %d:", n)
+		for k, i := range items {
+			if k > 0 {
+				fmt.Fprint(b, " ->")
+			}
+			fmt.Fprintf(b, " %v (%#x)", i.key, i.hash)
+		}
+		fmt.Fprintln(b)
+		n++
+	})
+	// bufio.Writer keeps the first error.
+	return b.Flush()
+}
+
+// DOT writes the layout of the buckets to w in the Graphviz DOT language, such
+// as for rendering with "dot -Tsvg". The buckets are drawn as an array, with
+// each bucket pointing to the chain of entries in it.
+func (d *Dictionary) DOT(w io.Writer) error {
+	d.removeExpired()
+
+	var labels []string
+	var edges []string
+	node := 0
+	d.store.layout(func(items []*item) {
+		bucket := len(labels)
+		labels = append(labels, fmt.Sprintf("<b%d> %d", bucket, bucket))
+		from := fmt.Sprintf("buckets:b%d", bucket)
+		for _, i := range items {
+			to := fmt.Sprintf("item%d", node)
+			edges = append(edges,
+				fmt.Sprintf("\t%s [label=\"%s|%#x\"];\n", to, dotEscape(fmt.Sprint(i.key)), i.hash),
+				fmt.Sprintf("\t%s -> %s;\n", from, to))
+			from = to
+			node++
+		}
+	})
+
+	b := bufio.NewWriter(w)
+	fmt.Fprint(b, "digraph dictionary {\n\trankdir=LR;\n\tnode [shape=record];\n")
+	fmt.Fprintf(b, "\tbuckets [label=\"%s\"];\n", strings.Join(labels, "|"))
+	for _, e := range edges {
+		fmt.Fprint(b, e)
+	}
+	fmt.Fprint(b, "}\n")
+	return b.Flush()
+}
+
+// dotEscape escapes the characters that are special in a quoted record label.
+var dotEscape = strings.NewReplacer(
+	`\`, `\\`, `"`, `\"`, `|`, `\|`, `{`, `\{`, `}`, `\}`, `<`, `\<`, `>`, `\>`, "\n", `\n`,
+).Replace
@@ -0,0 +1,91 @@
+package dictionary
+
+// Entry is a handle to the entry for a key, which may or may not be present,
+// returned by Dictionary.Entry. The key is looked up once, when the handle is
+// created, and the methods then act on the entry directly, so a read, modify,
+// write sequence does not look up the key again. A handle must not be used
+// after the dictionary has been changed other than through the handle.
+type Entry struct {
+	d   *Dictionary
+	key Hasher
+	h   uint64
+	// nil if the key is not present.
+	i *item
+}
+
+// Entry returns a handle to the entry for key.
+func (d *Dictionary) Entry(key Hasher) *Entry {
+	key, h, i := d.lookup(key)
+	return &Entry{d: d, key: key, h: h, i: i}
+}
+
+// Key returns the key of the entry.
+func (e *Entry) Key() Hasher {
+	return e.key
+}
+
+// Value returns the value of the entry. The second return value will be false
+// if the key is not present.
+func (e *Entry) Value() (interface{}, bool) {
+	if e.i == nil {
+		return nil, false
+	}
+	return e.i.value, true
+}
+
+// Exists reports whether the key is present.
+func (e *Entry) Exists() bool {
+	return e.i != nil
+}
+
+// OrInsert returns the value of the entry, first adding it with val if the key
+// is not present.
+func (e *Entry) OrInsert(val interface{}) interface{} {
+	if e.i != nil {
+		return e.i.value
+	}
+	e.insert(val)
+	return val
+}
+
+// OrInsertWith returns the value of the entry, first adding it with the value
+// returned by f if the key is not present. f is only called if needed.
+func (e *Entry) OrInsertWith(f func() interface{}) interface{} {
+	if e.i != nil {
+		return e.i.value
+	}
+	val := f()
+	e.insert(val)
+	return val
+}
+
+// SetValue sets the value of the entry, adding it if the key is not present.
+func (e *Entry) SetValue(val interface{}) {
+	if e.i == nil {
+		e.insert(val)
+		return
+	}
+	e.d.replace(e.i, val)
+}
+
+// Delete removes the entry. It returns false if the key was not present.
+func (e *Entry) Delete() bool {
+	if e.i == nil {
+		return false
+	}
+	e.d.delete(e.i)
+	e.i = nil
+	return true
+}
+
+// helper to add the entry.
+func (e *Entry) insert(val interface{}) {
+	i := e.d.newItem(e.key, e.h, val)
+	e.d.add(i)
+	e.i = i
+	if e.d.policy != nil {
+		// a bounded dictionary may have dropped or evicted the new entry
+		// right away.
+		e.i = e.d.store.find(e.key, e.h)
+	}
+}
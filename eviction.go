@@ -0,0 +1,142 @@
+package dictionary
+
+import "math/rand"
+
+// EvictionPolicy chooses which entries to evict from a bounded dictionary.
+// The dictionary tells the policy about the keys it holds, so policies
+// should not otherwise be shared between dictionaries.
+type EvictionPolicy interface {
+	// Touch is called when a key is added or used.
+	Touch(key Hasher)
+	// Remove is called when a key is removed from the dictionary, including
+	// when it is evicted.
+	Remove(key Hasher)
+	// Evictee returns the key that should be evicted next. It is only called
+	// when the dictionary is not empty.
+	Evictee() Hasher
+}
+
+// helper to create the index of keys used by the built in policies. It grows
+// as keys are added, so lookups stay fast however many entries the dictionary
+// is bounded to, without allocating for the bound up front.
+func newPolicyIndex() *Dictionary {
+	return New(WithIncrementalResize())
+}
+
+// Weigher returns the weight of an entry, such as its size in bytes.
+type Weigher func(key Hasher, val interface{}) int
+
+// SetMaxEntries bounds the dictionary to at most n entries. When an entry is
+// added to a full dictionary, entries are evicted as chosen by the eviction
+// policy, which is LRU unless set with SetEvictionPolicy.
+func SetMaxEntries(n int) OptionsFunc {
+	return func(d *Dictionary) {
+		d.maxEntries = n
+	}
+}
+
+// WithWeigher sets the function used to weigh entries for SetMaxWeight.
+func WithWeigher(w Weigher) OptionsFunc {
+	return func(d *Dictionary) {
+		d.weigher = w
+	}
+}
+
+// SetMaxWeight bounds the total weight of the entries, as given by the
+// weigher set with WithWeigher, to n. When the total weight exceeds n,
+// entries are evicted as chosen by the eviction policy, which is LRU unless
+// set with SetEvictionPolicy. An entry heavier than n is evicted as soon as it
+// is added.
+func SetMaxWeight(n int) OptionsFunc {
+	return func(d *Dictionary) {
+		d.maxWeight = n
+	}
+}
+
+// SetEvictionPolicy sets the policy used to choose which entries to evict.
+func SetEvictionPolicy(p EvictionPolicy) OptionsFunc {
+	return func(d *Dictionary) {
+		d.policy = p
+	}
+}
+
+// helper to tell the policy an item has been used, and renew its expiration
+// time if it has a sliding one.
+func (d *Dictionary) touch(i *item) {
+	d.renew(i)
+	if d.policy != nil {
+		d.policy.Touch(i.key)
+	}
+}
+
+// helper to evict items until the dictionary is within its bounds. If the
+// policy chooses a key that is not present, it is told to forget it, and
+// eviction stops until the next change, rather than asking again forever.
+func (d *Dictionary) evict() {
+	for d.overLimit() {
+		// the evictee is a stored key, so it is already normalized.
+		k := d.policy.Evictee()
+		i := d.find(k, d.hash(k))
+		if i == nil {
+			d.policy.Remove(k)
+			return
+		}
+		if d.observer != nil {
+			d.observer.Evict(i.key)
+		}
+		d.evicted(i, EvictCapacity)
+	}
+}
+
+func (d *Dictionary) overLimit() bool {
+	if d.count == 0 {
+		return false
+	}
+	return (d.maxEntries > 0 && d.count > d.maxEntries) ||
+		(d.maxWeight > 0 && d.weight > d.maxWeight)
+}
+
+// randomPolicy evicts a random entry.
+type randomPolicy struct {
+	keys []Hasher
+	// position of each key in keys.
+	index *Dictionary
+}
+
+// NewRandomPolicy returns an EvictionPolicy that evicts entries at random.
+func NewRandomPolicy() EvictionPolicy {
+	return &randomPolicy{
+		index: newPolicyIndex(),
+	}
+}
+
+func (p *randomPolicy) Touch(key Hasher) {
+	p.index.Update(key, func(old interface{}, exists bool) (interface{}, bool) {
+		if exists {
+			return old, false
+		}
+		p.keys = append(p.keys, key)
+		return len(p.keys) - 1, false
+	})
+}
+
+func (p *randomPolicy) Remove(key Hasher) {
+	v, ok := p.index.Delete(key)
+	if !ok {
+		return
+	}
+
+	// move the last key into the hole.
+	n := v.(int)
+	last := len(p.keys) - 1
+	if n != last {
+		p.keys[n] = p.keys[last]
+		p.index.Set(p.keys[n], n)
+	}
+	p.keys[last] = nil
+	p.keys = p.keys[:last]
+}
+
+func (p *randomPolicy) Evictee() Hasher {
+	return p.keys[rand.Intn(len(p.keys))]
+}
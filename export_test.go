@@ -0,0 +1,4 @@
+package dictionary
+
+// WithClock exposes withClock to the tests in dictionary_test.
+var WithClock = withClock
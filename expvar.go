@@ -0,0 +1,23 @@
+package dictionary
+
+import "expvar"
+
+// Var returns an expvar.Var that reports the Stats of the dictionary as
+// JSON. Unlike the methods of the dictionary, the Var takes the lock itself
+// while getting them, as it is read by the expvar handler rather than by the
+// caller. Code using the dictionary from other goroutines must use Lock and
+// Unlock too, and must not read the Var while holding the lock.
+func (d *Dictionary) Var() expvar.Var {
+	return expvar.Func(func() interface{} {
+		d.Lock()
+		defer d.Unlock()
+		return d.Stats()
+	})
+}
+
+// PublishExpvar publishes the Stats of the dictionary under name, so they are
+// served by the expvar handler, usually at /debug/vars. Like expvar.Publish,
+// it panics if name is already in use. See Var for the locking required.
+func (d *Dictionary) PublishExpvar(name string) {
+	expvar.Publish(name, d.Var())
+}
@@ -0,0 +1,49 @@
+package dictionary
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+)
+
+// Save writes the dictionary to the file at path using Encode. The file is
+// written to a temporary file in the same directory and then renamed, so path
+// always holds either the previous or the new contents.
+func (d *Dictionary) Save(path string) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	if err = d.Encode(w); err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Load reads a dictionary written by Save. Options are passed to Decode.
+func Load(path string, options ...OptionsFunc) (*Dictionary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Decode(bufio.NewReader(f), options...)
+}
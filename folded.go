@@ -0,0 +1,57 @@
+package dictionary
+
+import (
+	"hash/crc32"
+	"hash/maphash"
+	"strings"
+	"unicode"
+)
+
+// FoldedStringKey is a string key that is compared case-insensitively, using
+// Unicode case folding as strings.EqualFold does. It is useful for things like
+// header names and hostnames.
+type FoldedStringKey string
+
+// foldRune returns the smallest rune that is equal to r under simple case
+// folding, so all of the case variants of a rune map to the same one.
+func foldRune(r rune) rune {
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	return min
+}
+
+// folded returns the string with each rune replaced by its folded form.
+func (s FoldedStringKey) folded() string {
+	return strings.Map(foldRune, string(s))
+}
+
+// Hash generates a hash of the folded string using crc32
+func (s FoldedStringKey) Hash() uint32 {
+	return crc32.ChecksumIEEE([]byte(s.folded()))
+}
+
+// HashSeed generates a hash of the folded string using hash/maphash.
+func (s FoldedStringKey) HashSeed(seed maphash.Seed) uint64 {
+	return maphash.String(seed, s.folded())
+}
+
+// Equal reports whether v, which must also be a FoldedStringKey, is equal
+// to s ignoring case.
+func (s FoldedStringKey) Equal(v interface{}) bool {
+	return strings.EqualFold(string(s), string(v.(FoldedStringKey)))
+}
+
+// Less reports whether s sorts before v, which must also be a
+// FoldedStringKey, ignoring case.
+func (s FoldedStringKey) Less(v Hasher) bool {
+	return s.folded() < v.(FoldedStringKey).folded()
+}
+
+// String returns the string value of the key, as it was given.
+func (s FoldedStringKey) String() string {
+	return string(s)
+}
@@ -0,0 +1,66 @@
+package dictionary
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// the most entries shown by String and GoString.
+const maxFormatEntries = 100
+
+// String returns the entries formatted like {key: value, ...}. If all of the
+// keys implement Ordered and are of the same type, they are sorted; otherwise
+// the order is unspecified. Large dictionaries are cut short. Formatting does
+// not change the dictionary: expired entries are left out, but not removed.
+func (d *Dictionary) String() string {
+	return d.format("{", "%v: %v")
+}
+
+// GoString returns the entries formatted like a Go map literal, with keys and
+// values shown using %#v. It is used by the %#v verb.
+func (d *Dictionary) GoString() string {
+	return d.format("dictionary.Dictionary{", "%#v: %#v")
+}
+
+// helper to format the entries, each using entry, between open and a closing
+// brace.
+func (d *Dictionary) format(open, entry string) string {
+	var items []Item
+	var keys keyTypes
+	// the store is read directly, rather than using walk, so that expired
+	// entries are not removed and no hooks are called while formatting.
+	if d.store != nil {
+		var now time.Time
+		if d.expiring > 0 {
+			now = d.now()
+		}
+		c := d.store.cursor()
+		for i := c.next(); i != nil; i = c.next() {
+			if i.expired(now) {
+				continue
+			}
+			keys.add(i.key)
+			items = append(items, Item{Key: i.key, Value: i.value})
+		}
+	}
+	if keys.ordered() {
+		sort.Sort(&itemSorter{items: items, less: OrderedLess})
+	}
+
+	var b strings.Builder
+	b.WriteString(open)
+	for n, i := range items {
+		if n > 0 {
+			b.WriteString(", ")
+		}
+		if n == maxFormatEntries {
+			fmt.Fprintf(&b, "... %d more", len(items)-n)
+			break
+		}
+		fmt.Fprintf(&b, entry, i.Key, i.Value)
+	}
+	b.WriteString("}")
+	return b.String()
+}
@@ -0,0 +1,231 @@
+package dictionary
+
+import (
+	"hash/maphash"
+	"sort"
+)
+
+// Frozen is an immutable dictionary, built by Freeze, for static lookup
+// tables such as keyword maps. Its entries are placed using a minimal perfect
+// hash, so every lookup examines exactly one slot. It is safe for concurrent
+// use, as nothing about it changes after it is built.
+type Frozen struct {
+	seed       maphash.Seed
+	stringHash StringHashFunc
+	normalizer NormalizeFunc
+	// the displacement for each group of hashes.
+	displace []uint32
+	slots    []frozenSlot
+	// keys whose hash is shared with the key in their slot. These are rare,
+	// and only possible for keys with a poor hash.
+	collisions []frozenSlot
+	count      int
+}
+
+type frozenSlot struct {
+	hash  uint64
+	key   Hasher
+	value interface{}
+	used  bool
+}
+
+const (
+	// the average number of hashes in each group. Larger groups need less
+	// space for displacements, but take longer to place.
+	frozenGroupSize = 4
+	// how many displacements to try for a group before using a larger table.
+	frozenMaxTries = 1 << 16
+	// set in the displacement of a group with a single hash, which is placed
+	// directly in the slot given by the rest of the bits.
+	frozenDirect = 1 << 31
+)
+
+// Freeze builds a Frozen dictionary holding the current entries of the
+// dictionary. Keys are hashed the same way, so the same seed, string hash and
+// key normalizer are used. Later changes to the dictionary are not seen by the
+// Frozen one.
+func (d *Dictionary) Freeze() *Frozen {
+	f := &Frozen{
+		seed:       d.seed,
+		stringHash: d.stringHash,
+		normalizer: d.normalizer,
+	}
+
+	var entries []frozenSlot
+	d.walk(func(i *item) bool {
+		entries = append(entries, frozenSlot{hash: i.hash, key: i.key, value: i.value, used: true})
+		return true
+	})
+	f.count = len(entries)
+	f.build(entries)
+	return f
+}
+
+// frozenIndex returns the slot for h, given the displacement for its group.
+func frozenIndex(h uint64, displace uint32, n int) int {
+	if displace&frozenDirect != 0 {
+		return int(displace &^ frozenDirect)
+	}
+	return int(mix(h^uint64(displace)*0x9e3779b97f4a7c15) % uint64(n))
+}
+
+// build places the entries using "hash and displace": hashes are split into
+// small groups, and the groups, largest first, each search for a displacement
+// that moves all of their hashes to free slots.
+func (f *Frozen) build(entries []frozenSlot) {
+	// entries with the same hash cannot be told apart by any displacement.
+	sort.Slice(entries, func(a, b int) bool { return entries[a].hash < entries[b].hash })
+	var unique []frozenSlot
+	for n, e := range entries {
+		if n > 0 && e.hash == entries[n-1].hash {
+			f.collisions = append(f.collisions, e)
+			continue
+		}
+		unique = append(unique, e)
+	}
+
+	numGroups := (len(unique) + frozenGroupSize - 1) / frozenGroupSize
+	if numGroups == 0 {
+		numGroups = 1
+	}
+	groups := make([][]frozenSlot, numGroups)
+	for _, e := range unique {
+		g := mix(e.hash) % uint64(numGroups)
+		groups[g] = append(groups[g], e)
+	}
+	order := make([]int, numGroups)
+	for g := range order {
+		order[g] = g
+	}
+	sort.SliceStable(order, func(a, b int) bool { return len(groups[order[a]]) > len(groups[order[b]]) })
+
+	size := len(unique)
+	if size == 0 {
+		size = 1
+	}
+	for !f.place(groups, order, size) {
+		// rare, but possible for an unlucky set of hashes. A table with
+		// some empty slots is still only one probe per lookup.
+		size += size/8 + 1
+	}
+}
+
+// place tries to place the groups in a table of size slots.
+func (f *Frozen) place(groups [][]frozenSlot, order []int, size int) bool {
+	f.slots = make([]frozenSlot, size)
+	f.displace = make([]uint32, len(groups))
+	taken := make([]int, 0, frozenGroupSize*2)
+	free := 0
+	for _, g := range order {
+		group := groups[g]
+		if len(group) == 0 {
+			break
+		}
+		if len(group) == 1 {
+			// the groups are sorted, so only single hashes are left. Once
+			// the table is nearly full, finding a displacement to a free
+			// slot could take a long time, so use the free slots directly.
+			for f.slots[free].used {
+				free++
+			}
+			f.slots[free] = group[0]
+			f.displace[g] = frozenDirect | uint32(free)
+			continue
+		}
+		placed := false
+		for d := uint32(0); d < frozenMaxTries && !placed; d++ {
+			taken = taken[:0]
+			placed = true
+			for _, e := range group {
+				n := frozenIndex(e.hash, d, size)
+				if f.slots[n].used {
+					placed = false
+					break
+				}
+				// mark it now, so later entries of the group see it.
+				f.slots[n].used = true
+				taken = append(taken, n)
+			}
+			if !placed {
+				for _, n := range taken {
+					f.slots[n].used = false
+				}
+				continue
+			}
+			for k, n := range taken {
+				f.slots[n] = group[k]
+			}
+			f.displace[g] = d
+		}
+		if !placed {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Frozen) find(key Hasher) *frozenSlot {
+	if f.normalizer != nil {
+		key = f.normalizer(key)
+	}
+	h := hashWith(key, f.seed, f.stringHash)
+	g := mix(h) % uint64(len(f.displace))
+	s := &f.slots[frozenIndex(h, f.displace[g], len(f.slots))]
+	if !s.used || s.hash != h {
+		return nil
+	}
+	if key.Equal(s.key) {
+		return s
+	}
+	for n := range f.collisions {
+		if c := &f.collisions[n]; c.hash == h && key.Equal(c.key) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Get returns an item from the dictionary. The second return value will be
+// false if not found.
+func (f *Frozen) Get(key Hasher) (interface{}, bool) {
+	if s := f.find(key); s != nil {
+		return s.value, true
+	}
+	return nil, false
+}
+
+// Contains reports whether key is present in the dictionary.
+func (f *Frozen) Contains(key Hasher) bool {
+	return f.find(key) != nil
+}
+
+// Len returns the number of entries in the dictionary.
+func (f *Frozen) Len() int {
+	return f.count
+}
+
+// Each executes the function on each element. Error returned will be any
+// error the EachFunc returned to stop iteration.
+func (f *Frozen) Each(fn EachFunc) error {
+	for _, slots := range [][]frozenSlot{f.slots, f.collisions} {
+		for _, s := range slots {
+			if !s.used {
+				continue
+			}
+			if err := fn(s.key, s.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Keys returns all the keys in the dictionary.
+func (f *Frozen) Keys() []Hasher {
+	keys := make([]Hasher, 0, f.count)
+	f.Each(func(k Hasher, _ interface{}) error {
+		keys = append(keys, k)
+		return nil
+	})
+	return keys
+}
@@ -0,0 +1,65 @@
+package dictionary
+
+// Filter returns a new dictionary containing the entries for which pred
+// returns true.
+func (d *Dictionary) Filter(pred PredicateFunc) *Dictionary {
+	return d.filter(func(i *item) bool {
+		return pred(i.key, i.value)
+	})
+}
+
+// MapValues returns a new dictionary with the same keys, and values replaced
+// by the result of calling f on each entry.
+func (d *Dictionary) MapValues(f MapFunc) *Dictionary {
+	out := d.newLike()
+	d.walk(func(i *item) bool {
+		// addItem copies the item, so give it one with the new value.
+		c := *i
+		c.value = f(i.key, i.value)
+		out.addItem(d, &c)
+		return true
+	})
+	return out
+}
+
+// Reduce combines the entries into a single value. f is called for each
+// entry with the result so far, starting with acc, and the final result is
+// returned. The order of the entries is unspecified, so f should not depend
+// on it.
+func (d *Dictionary) Reduce(acc interface{}, f ReduceFunc) interface{} {
+	d.walk(func(i *item) bool {
+		acc = f(acc, i.key, i.value)
+		return true
+	})
+	return acc
+}
+
+// Find returns an entry for which pred returns true, stopping at the first
+// one found. The last return value will be false if there is none. If several
+// entries match, which one is returned is unspecified.
+func (d *Dictionary) Find(pred PredicateFunc) (Hasher, interface{}, bool) {
+	var found *item
+	d.walk(func(i *item) bool {
+		if pred(i.key, i.value) {
+			found = i
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return nil, nil, false
+	}
+	return found.key, found.value, true
+}
+
+// KeysWhere returns the keys of the entries for which pred returns true.
+func (d *Dictionary) KeysWhere(pred PredicateFunc) []Hasher {
+	var keys []Hasher
+	d.walk(func(i *item) bool {
+		if pred(i.key, i.value) {
+			keys = append(keys, i.key)
+		}
+		return true
+	})
+	return keys
+}
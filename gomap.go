@@ -0,0 +1,231 @@
+package dictionary
+
+import "reflect"
+
+// MapKeyer may be implemented by keys to be stored with the GoMap backend.
+// MapKey returns a comparable value, such as a string or a struct of
+// comparable fields, that is == for keys that are Equal and != otherwise.
+// Keys that do not implement it are used as map keys themselves, and == must
+// agree with Equal. Keys whose map key is nil or not comparable, such as a
+// slice, are kept in chains by hash instead and compared with Equal.
+type MapKeyer interface {
+	Hasher
+	MapKey() interface{}
+}
+
+// the map keys of the built in keys that are not comparable, or whose Equal
+// is not ==. They are distinct types, so they never equal the map key of
+// another type of key.
+type (
+	bytesMapKey  string
+	foldedMapKey string
+)
+
+// MapKey returns the bytes as a comparable value.
+func (b BytesKey) MapKey() interface{} {
+	return bytesMapKey(b)
+}
+
+// MapKey returns the folded string, so keys that differ only in case are the
+// same.
+func (s FoldedStringKey) MapKey() interface{} {
+	return foldedMapKey(s.folded())
+}
+
+// compositeMapKey is the map key of a CompositeKey, as a list of the map keys
+// of its parts.
+type compositeMapKey struct {
+	part interface{}
+	rest interface{}
+}
+
+// MapKey returns the map keys of the parts, or nil if any of them are not
+// comparable.
+func (c CompositeKey) MapKey() interface{} {
+	var k interface{} = compositeMapKey{}
+	for n := len(c) - 1; n >= 0; n-- {
+		part, ok := mapKey(c[n])
+		if !ok {
+			return nil
+		}
+		k = compositeMapKey{part: part, rest: k}
+	}
+	return k
+}
+
+// MapKey returns the map key of the wrapped key.
+func (c *CachedHasher) MapKey() interface{} {
+	k, _ := mapKey(c.Key)
+	return k
+}
+
+// mapKey returns the value a key is stored under in a mapTable, and whether
+// it can be used as a map key.
+func mapKey(key Hasher) (interface{}, bool) {
+	var k interface{} = key
+	if m, ok := key.(MapKeyer); ok {
+		k = m.MapKey()
+	}
+	if k == nil || !reflect.TypeOf(k).Comparable() {
+		return nil, false
+	}
+	return k, true
+}
+
+// mapTable is a store backed by a built in Go map, for the GoMap backend. The
+// map does its own hashing, so the dictionary's hash is only kept for
+// callers such as Freeze that use it.
+type mapTable struct {
+	items map[interface{}]*item
+	// the items whose keys cannot be used as map keys, by hash.
+	chains map[uint64][]*item
+	// the number of items in both items and chains.
+	count int
+	// the size the map was created for, doubled as it fills up, so the
+	// capacity grows the way the map does.
+	size int
+}
+
+func newMapTable(n uint32) *mapTable {
+	return &mapTable{
+		items:  make(map[interface{}]*item, n),
+		chains: make(map[uint64][]*item),
+		size:   int(n),
+	}
+}
+
+func (t *mapTable) find(key Hasher, hash uint64) *item {
+	k, ok := mapKey(key)
+	if ok {
+		return t.items[k]
+	}
+	for _, i := range t.chains[hash] {
+		if i.key.Equal(key) {
+			return i
+		}
+	}
+	return nil
+}
+
+func (t *mapTable) insert(i *item) {
+	if k, ok := mapKey(i.key); ok {
+		if _, found := t.items[k]; !found {
+			t.count++
+		}
+		t.items[k] = i
+	} else {
+		c := t.chains[i.hash]
+		for n, o := range c {
+			if o.key.Equal(i.key) {
+				c[n] = i
+				return
+			}
+		}
+		t.chains[i.hash] = append(c, i)
+		t.count++
+	}
+	for t.count > t.size {
+		t.size = max(t.size*2, 1)
+	}
+}
+
+func (t *mapTable) delete(i *item) bool {
+	k, ok := mapKey(i.key)
+	if ok {
+		if t.items[k] != i {
+			return false
+		}
+		delete(t.items, k)
+		t.count--
+		return true
+	}
+	c := t.chains[i.hash]
+	for n, o := range c {
+		if o == i {
+			if len(c) == 1 {
+				delete(t.chains, i.hash)
+			} else {
+				t.chains[i.hash] = append(c[:n:n], c[n+1:]...)
+			}
+			t.count--
+			return true
+		}
+	}
+	return false
+}
+
+func (t *mapTable) capacity() int {
+	return t.size
+}
+
+// the map's buckets are not visible, so each entry is counted as a bucket of
+// its own, and each chain as one bucket.
+func (t *mapTable) stats(s *Stats) {
+	s.Buckets += len(t.items) + len(t.chains)
+	for range t.items {
+		s.record(1)
+	}
+	for _, c := range t.chains {
+		s.record(len(c))
+	}
+}
+
+func (t *mapTable) chain(i *item) (int, int) {
+	if _, ok := mapKey(i.key); ok {
+		return 0, 1
+	}
+	c := t.chains[i.hash]
+	for n, o := range c {
+		if o == i {
+			return n, len(c)
+		}
+	}
+	return 0, len(c)
+}
+
+func (t *mapTable) layout(f func(items []*item)) {
+	for _, i := range t.items {
+		f([]*item{i})
+	}
+	for _, c := range t.chains {
+		f(c)
+	}
+}
+
+// the cursor uses a reflect.MapIter, as a range loop cannot be paused. Like a
+// range loop, deleting the item last returned does not affect the rest of the
+// iteration.
+func (t *mapTable) cursor() cursor {
+	return &mapCursor{
+		iter:   reflect.ValueOf(t.items).MapRange(),
+		chains: reflect.ValueOf(t.chains).MapRange(),
+	}
+}
+
+type mapCursor struct {
+	iter   *reflect.MapIter
+	chains *reflect.MapIter
+	// a copy of the chain being returned, so deletes do not shift it.
+	chain []*item
+	// a MapIter panics if Next is called once it is exhausted.
+	itemsDone, chainsDone bool
+}
+
+func (c *mapCursor) next() *item {
+	if !c.itemsDone {
+		if c.iter.Next() {
+			return c.iter.Value().Interface().(*item)
+		}
+		c.itemsDone = true
+	}
+	for len(c.chain) == 0 {
+		if c.chainsDone || !c.chains.Next() {
+			c.chainsDone = true
+			return nil
+		}
+		c.chain = append([]*item(nil), c.chains.Value().Interface().([]*item)...)
+	}
+	i := c.chain[0]
+	c.chain = c.chain[1:]
+	return i
+}
@@ -0,0 +1,65 @@
+package dictionary
+
+type (
+	// HookFunc is called with an entry that has been added to or removed from
+	// a dictionary.
+	HookFunc func(key Hasher, val interface{})
+
+	// ReplaceHookFunc is called with the old and new values of an entry
+	// whose value has been replaced.
+	ReplaceHookFunc func(key Hasher, old, new interface{})
+
+	// EvictFunc is called with an entry that the dictionary removed or
+	// turned away on its own, and the reason why.
+	EvictFunc func(key Hasher, val interface{}, reason EvictReason)
+
+	// EvictReason says why an EvictFunc was called.
+	EvictReason int
+)
+
+const (
+	// EvictCapacity is given for an entry evicted to keep the dictionary
+	// within the bounds set by SetMaxEntries or SetMaxWeight.
+	EvictCapacity EvictReason = iota
+	// EvictExpired is given for an entry removed because it expired.
+	EvictExpired
+	// EvictRejected is given for a new entry that WithTinyLFU did not
+	// admit, so it was never added.
+	EvictRejected
+)
+
+// WithOnInsert sets a function to be called when an entry is added. Like the
+// other hooks, it is called synchronously while the dictionary is being
+// changed, so it must not use the dictionary. Hooks can be used to keep
+// derived indexes or write-through caches in sync with the dictionary.
+func WithOnInsert(f HookFunc) OptionsFunc {
+	return func(d *Dictionary) {
+		d.onInsert = f
+	}
+}
+
+// WithOnReplace sets a function to be called when the value of an existing
+// entry is replaced.
+func WithOnReplace(f ReplaceHookFunc) OptionsFunc {
+	return func(d *Dictionary) {
+		d.onReplace = f
+	}
+}
+
+// WithOnEvict sets a function to be called when the dictionary removes an
+// entry on its own, because it was evicted or expired, or turns one away,
+// such as to close files or connections held by the value. It is called after
+// the entry has been removed, and after WithOnDelete's function.
+func WithOnEvict(f EvictFunc) OptionsFunc {
+	return func(d *Dictionary) {
+		d.onEvict = f
+	}
+}
+
+// WithOnDelete sets a function to be called when an entry is removed,
+// including when it is evicted or expires.
+func WithOnDelete(f HookFunc) OptionsFunc {
+	return func(d *Dictionary) {
+		d.onDelete = f
+	}
+}
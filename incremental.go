@@ -0,0 +1,132 @@
+package dictionary
+
+// WithIncrementalResize grows the dictionary as entries are added, keeping
+// about one entry per bucket. Rather than moving every entry to the larger
+// table at once, which can pause for a long time with millions of entries,
+// both tables are kept and a few entries are moved on each change, as Redis
+// does. Lookups check both tables until all of the entries have been moved.
+// This works with every backend; those that grow on their own are grown
+// before they would need to.
+func WithIncrementalResize() OptionsFunc {
+	return func(d *Dictionary) {
+		d.incremental = true
+	}
+}
+
+// the number of items moved to the new table on each change. The new table
+// has room for twice as many items, so all of them are moved well before it
+// fills up.
+const rehashStep = 8
+
+// rehashStore is used while a dictionary is growing incrementally. New items
+// are added to the new store, and items are moved from the old store a few at
+// a time.
+type rehashStore struct {
+	old, new store
+	// the items of old still to be moved.
+	c cursor
+}
+
+func newRehashStore(old, new store) *rehashStore {
+	return &rehashStore{
+		old: old,
+		new: new,
+		c:   old.cursor(),
+	}
+}
+
+func (r *rehashStore) find(key Hasher, h uint64) *item {
+	if i := r.new.find(key, h); i != nil {
+		return i
+	}
+	return r.old.find(key, h)
+}
+
+func (r *rehashStore) insert(i *item) {
+	r.new.insert(i)
+}
+
+func (r *rehashStore) delete(i *item) bool {
+	return r.new.delete(i) || r.old.delete(i)
+}
+
+func (r *rehashStore) capacity() int {
+	return r.new.capacity()
+}
+
+func (r *rehashStore) stats(s *Stats) {
+	r.old.stats(s)
+	r.new.stats(s)
+}
+
+// items are only inserted into the new store, so that is where their chain is
+// reported from.
+func (r *rehashStore) chain(i *item) (int, int) {
+	return r.new.chain(i)
+}
+
+// the buckets of the new store follow those of the old one.
+func (r *rehashStore) layout(f func(items []*item)) {
+	r.old.layout(f)
+	r.new.layout(f)
+}
+
+// items only move from old to new, so visiting old first sees each item once.
+func (r *rehashStore) cursor() cursor {
+	return &rehashCursor{
+		old: r.old.cursor(),
+		new: r.new.cursor(),
+	}
+}
+
+// step moves up to n items from the old store to the new one. It returns true
+// once there are none left.
+func (r *rehashStore) step(n int) bool {
+	for ; n > 0; n-- {
+		i := r.c.next()
+		if i == nil {
+			return true
+		}
+		// the item may have been deleted since the cursor reached it.
+		if r.old.delete(i) {
+			r.new.insert(i)
+		}
+	}
+	return false
+}
+
+type rehashCursor struct {
+	old, new cursor
+}
+
+func (c *rehashCursor) next() *item {
+	if c.old != nil {
+		if i := c.old.next(); i != nil {
+			return i
+		}
+		c.old = nil
+	}
+	return c.new.next()
+}
+
+// helper to grow the dictionary, if it resizes incrementally, and to move some
+// items if it is in the middle of growing. Items are not moved while walking,
+// so no item is seen twice.
+func (d *Dictionary) resizeStep() {
+	if !d.incremental {
+		return
+	}
+	if r, ok := d.store.(*rehashStore); ok {
+		if d.walking == 0 && r.step(rehashStep) {
+			d.store = r.new
+		}
+		return
+	}
+	if d.count >= d.store.capacity() {
+		d.numBuckets = d.bucketsFor(2 * d.count)
+		d.store = newRehashStore(d.store, d.newStore(d.numBuckets))
+		if d.observer != nil {
+			d.observer.Rehash(d.store.capacity())
+		}
+	}
+}
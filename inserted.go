@@ -0,0 +1,113 @@
+package dictionary
+
+import "time"
+
+// helper to add an item to the back of the insertion order list.
+func (d *Dictionary) pushBack(i *item) {
+	i.prev = d.newest
+	i.next = nil
+	if d.newest != nil {
+		d.newest.next = i
+	} else {
+		d.oldest = i
+	}
+	d.newest = i
+}
+
+// helper to remove an item from the insertion order list.
+func (d *Dictionary) unlink(i *item) {
+	if i.prev != nil {
+		i.prev.next = i.next
+	} else {
+		d.oldest = i.next
+	}
+	if i.next != nil {
+		i.next.prev = i.prev
+	} else {
+		d.newest = i.prev
+	}
+	i.prev, i.next = nil, nil
+}
+
+// helper to call f on each item in insertion order until it returns false.
+// Expired items are removed rather than passed to f. f may remove the item it
+// is passed.
+func (d *Dictionary) walkInserted(f func(i *item) bool) {
+	var now time.Time
+	if d.expiring > 0 {
+		now = d.now()
+	}
+	for i := d.oldest; i != nil; {
+		// i may be removed, and reused, by f.
+		next := i.next
+		if i.expired(now) {
+			d.expire(i)
+		} else if !f(i) {
+			return
+		}
+		i = next
+	}
+}
+
+// helper to find the first unexpired item from one end of the insertion order
+// list, removing any expired ones on the way.
+func (d *Dictionary) end(newest bool) *item {
+	now := d.now()
+	for {
+		i := d.oldest
+		if newest {
+			i = d.newest
+		}
+		if i == nil || !i.expired(now) {
+			return i
+		}
+		d.expire(i)
+	}
+}
+
+// Oldest returns the entry added longest ago. The last return value will be
+// false if the dictionary is empty, or does not use InsertionOrder.
+func (d *Dictionary) Oldest() (Hasher, interface{}, bool) {
+	if i := d.end(false); i != nil {
+		return i.key, i.value, true
+	}
+	return nil, nil, false
+}
+
+// Newest returns the entry added most recently. The last return value will be
+// false if the dictionary is empty, or does not use InsertionOrder.
+func (d *Dictionary) Newest() (Hasher, interface{}, bool) {
+	if i := d.end(true); i != nil {
+		return i.key, i.value, true
+	}
+	return nil, nil, false
+}
+
+// PopOldest removes the entry added longest ago and returns it, as for a FIFO
+// queue. The last return value will be false if the dictionary is empty, or
+// does not use InsertionOrder.
+func (d *Dictionary) PopOldest() (Hasher, interface{}, bool) {
+	i := d.end(false)
+	if i == nil {
+		return nil, nil, false
+	}
+	key, val := i.key, i.value
+	d.delete(i)
+	return key, val, true
+}
+
+// MoveToBack moves key to the back of the insertion order, as if it had just
+// been added, such as to track recency. It returns false if the key is not
+// present, or the dictionary does not use InsertionOrder.
+func (d *Dictionary) MoveToBack(key Hasher) bool {
+	if d.order != InsertionOrder {
+		return false
+	}
+	_, _, i := d.lookup(key)
+	if i == nil {
+		return false
+	}
+	d.unlink(i)
+	d.pushBack(i)
+	return true
+}
@@ -0,0 +1,54 @@
+package dictionary
+
+// Iterator is a cursor over the entries in a dictionary. It is an
+// alternative to Each for callers that need to control when the next entry
+// is fetched. The order of iteration is unspecified. The dictionary must not
+// be modified while an iterator is in use.
+type Iterator struct {
+	d *Dictionary
+	c cursor
+	i *item
+}
+
+// Iterator returns an iterator positioned before the first entry. Next must
+// be called before Key or Value.
+func (d *Dictionary) Iterator() *Iterator {
+	it := &Iterator{d: d}
+	it.Reset()
+	return it
+}
+
+// Next advances the iterator to the next entry. It returns false when there
+// are no more entries. Expired entries are skipped.
+func (it *Iterator) Next() bool {
+	if it.c == nil {
+		return false
+	}
+	now := it.d.now()
+	for it.i = it.c.next(); it.i != nil; it.i = it.c.next() {
+		if !it.i.expired(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Key returns the key of the current entry.
+func (it *Iterator) Key() Hasher {
+	return it.i.key
+}
+
+// Value returns the value of the current entry.
+func (it *Iterator) Value() interface{} {
+	return it.i.value
+}
+
+// Reset moves the iterator back before the first entry.
+func (it *Iterator) Reset() {
+	it.c = nil
+	it.i = nil
+	// a zero Dictionary has no store, and so no entries.
+	if it.d.store != nil {
+		it.c = it.d.store.cursor()
+	}
+}
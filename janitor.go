@@ -0,0 +1,100 @@
+package dictionary
+
+import "time"
+
+// janitor periodically removes expired items.
+type janitor struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// WithJanitor starts a goroutine that removes expired entries every
+// interval, so memory is reclaimed even for entries that are never used
+// again. The janitor holds the dictionary's lock while it runs, so all other
+// use of the dictionary must hold the lock as well. Close stops the janitor.
+func WithJanitor(interval time.Duration) OptionsFunc {
+	return func(d *Dictionary) {
+		d.janitorInterval = interval
+	}
+}
+
+// Lock locks the dictionary. The dictionary does not lock itself, but Lock
+// and Unlock are provided for callers to use when sharing a dictionary
+// between goroutines. The janitor uses them as well.
+func (d *Dictionary) Lock() {
+	d.mu.Lock()
+}
+
+// Unlock unlocks the dictionary.
+func (d *Dictionary) Unlock() {
+	d.mu.Unlock()
+}
+
+// Close stops the janitor, if one is running, and waits for it to exit. It
+// may be called with or without the lock held. It always returns nil.
+func (d *Dictionary) Close() error {
+	if d.janitor != nil {
+		close(d.janitor.stop)
+		<-d.janitor.done
+		d.janitor = nil
+	}
+	return nil
+}
+
+func (d *Dictionary) startJanitor() {
+	j := &janitor{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	d.janitor = j
+
+	go func() {
+		defer close(j.done)
+
+		t := time.NewTicker(d.janitorInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-t.C:
+				if !j.lock(d) {
+					return
+				}
+				d.removeExpired()
+				d.Unlock()
+			}
+		}
+	}()
+}
+
+// lock takes the dictionary's lock, unless the janitor is stopped first, in
+// which case it returns false. This lets Close be called while holding the
+// lock. The lock is still taken and released in the background once it is
+// free.
+func (j *janitor) lock(d *Dictionary) bool {
+	locked := make(chan struct{})
+	go func() {
+		d.Lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		return true
+	case <-j.stop:
+		go func() {
+			<-locked
+			d.Unlock()
+		}()
+		return false
+	}
+}
+
+// helper to remove all expired items.
+func (d *Dictionary) removeExpired() {
+	if d.expiring > 0 {
+		// walking removes any expired items.
+		d.walk(func(*item) bool { return true })
+	}
+}
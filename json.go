@@ -0,0 +1,149 @@
+package dictionary
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+type (
+	// KeyEncoder converts a key to a string so it can be used as a JSON
+	// object key.
+	KeyEncoder func(Hasher) (string, error)
+
+	// KeyDecoder converts a JSON object key back into a key.
+	KeyDecoder func(string) (Hasher, error)
+)
+
+// SetKeyEncoding sets the functions used to convert keys to and from strings
+// when marshaling to JSON. By default, only StringKey keys are supported,
+// unless WithKeyCodec is set.
+func SetKeyEncoding(enc KeyEncoder, dec KeyDecoder) OptionsFunc {
+	return func(d *Dictionary) {
+		d.keyEncoder = enc
+		d.keyDecoder = dec
+	}
+}
+
+func (d *Dictionary) encodeKey(key Hasher) (string, error) {
+	if d.keyEncoder != nil {
+		return d.keyEncoder(key)
+	}
+	if d.keyCodec != nil {
+		return d.codeJSONKey(key)
+	}
+	if s, ok := key.(StringKey); ok {
+		return string(s), nil
+	}
+	return "", fmt.Errorf("dictionary: cannot encode key of type %T", key)
+}
+
+func (d *Dictionary) decodeKey(s string) (Hasher, error) {
+	if d.keyDecoder != nil {
+		return d.keyDecoder(s)
+	}
+	if d.keyCodec != nil {
+		return d.keyCodec.DecodeKey([]byte(s))
+	}
+	return StringKey(s), nil
+}
+
+// MarshalJSON encodes the dictionary as a JSON object. The object keys are
+// sorted, so the output is stable.
+func (d *Dictionary) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, d.Len())
+	var err error
+	d.walk(func(i *item) bool {
+		var k string
+		if k, err = d.encodeKey(i.key); err != nil {
+			return false
+		}
+		m[k] = i.value
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	// encoding/json sorts map keys for us.
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes a JSON object into the dictionary. Like with maps,
+// the entries are added to any already in the dictionary. Values are decoded
+// as they would be into an interface{}.
+func (d *Dictionary) UnmarshalJSON(data []byte) error {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	if d.store == nil {
+		d.init()
+	}
+
+	// add in a stable order, so the bucket layout does not depend on map
+	// iteration order.
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key, err := d.decodeKey(k)
+		if err != nil {
+			return err
+		}
+		d.Set(key, m[k])
+	}
+	return nil
+}
+
+// DecodeJSONObject reads a JSON object from dec one entry at a time, so large
+// objects can be loaded without holding their encoding in memory. Each value
+// is decoded into the result of valueFactory, which must return a pointer, and
+// that pointer is stored. If valueFactory is nil, values are decoded as they
+// would be into an interface{}. dec is left positioned after the object, so a
+// stream of objects can be read by calling DecodeJSONObject repeatedly. The
+// dictionary is created by passing options to New, so keys are decoded with
+// its KeyCodec, if any.
+func DecodeJSONObject(dec *json.Decoder, valueFactory func() interface{}, options ...OptionsFunc) (*Dictionary, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("dictionary: expected a JSON object, got %v", tok)
+	}
+
+	d := New(options...)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		// object keys are always strings.
+		key, err := d.decodeKey(tok.(string))
+		if err != nil {
+			return nil, err
+		}
+
+		var v interface{}
+		if valueFactory != nil {
+			v = valueFactory()
+			err = dec.Decode(v)
+		} else {
+			err = dec.Decode(&v)
+		}
+		if err != nil {
+			return nil, err
+		}
+		d.Set(key, v)
+	}
+
+	// the closing brace.
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
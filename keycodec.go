@@ -0,0 +1,69 @@
+package dictionary
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// KeyCodec converts keys to and from bytes, so dictionaries with key types
+// other than StringKey can be marshaled and unmarshaled. It is used by
+// MarshalJSON and UnmarshalJSON, unless SetKeyEncoding is also set, and by
+// MarshalBinary, UnmarshalBinary, Encode and Decode, which then do not need
+// the key types to be registered with gob.
+type KeyCodec interface {
+	// EncodeKey returns the encoded form of a key. For JSON, it must be
+	// valid UTF-8.
+	EncodeKey(Hasher) ([]byte, error)
+	// DecodeKey returns the key for an encoded form returned by EncodeKey.
+	DecodeKey([]byte) (Hasher, error)
+}
+
+// ErrNoKeyCodec is returned when decoding data whose keys were encoded with a
+// KeyCodec into a dictionary without one.
+var ErrNoKeyCodec = errors.New("dictionary: keys were encoded with a KeyCodec, but none is set")
+
+// WithKeyCodec sets the KeyCodec used to marshal and unmarshal keys. The same
+// codec must be set when decoding as when encoding.
+func WithKeyCodec(c KeyCodec) OptionsFunc {
+	return func(d *Dictionary) {
+		d.keyCodec = c
+	}
+}
+
+// codedItem is an entry with its key encoded by a KeyCodec, as it is written
+// by the gob encoders.
+type codedItem struct {
+	Key   []byte
+	Value interface{}
+}
+
+// helper to encode an item's key with the KeyCodec.
+func (d *Dictionary) codeItem(i *item) (codedItem, error) {
+	k, err := d.keyCodec.EncodeKey(i.key)
+	return codedItem{Key: k, Value: i.value}, err
+}
+
+// helper to add an entry whose key was encoded with the KeyCodec.
+func (d *Dictionary) setCoded(c codedItem) error {
+	if d.keyCodec == nil {
+		return ErrNoKeyCodec
+	}
+	key, err := d.keyCodec.DecodeKey(c.Key)
+	if err != nil {
+		return err
+	}
+	d.Set(key, c.Value)
+	return nil
+}
+
+// helper to encode a key with the KeyCodec as a JSON object key.
+func (d *Dictionary) codeJSONKey(key Hasher) (string, error) {
+	b, err := d.keyCodec.EncodeKey(key)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(b) {
+		return "", errors.New("dictionary: KeyCodec returned invalid UTF-8 for a JSON key")
+	}
+	return string(b), nil
+}
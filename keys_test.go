@@ -0,0 +1,241 @@
+package dictionary_test
+
+import (
+	"hash/maphash"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bakins/dictionary"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumericKeys(t *testing.T) {
+	i := dictionary.New()
+	u := dictionary.New()
+	f := dictionary.New()
+
+	for n := 0; n < 1000; n++ {
+		i.Set(dictionary.Int64Key(n), n)
+		u.Set(dictionary.Uint64Key(n), n)
+		f.Set(dictionary.Float64Key(n)/2, n)
+	}
+
+	v, ok := i.Get(dictionary.Int64Key(42))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, 42, v.(int), "unexpected value")
+
+	v, ok = u.Get(dictionary.Uint64Key(42))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, 42, v.(int), "unexpected value")
+
+	v, ok = f.Get(dictionary.Float64Key(21.5))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, 43, v.(int), "unexpected value")
+
+	v, ok = f.Get(dictionary.Float64Key(math.Copysign(0, -1)))
+	require.Equal(t, true, ok, "-0 should equal 0")
+	require.Equal(t, 0, v.(int), "unexpected value")
+
+	// sequential keys should be spread evenly, unlike the intKey in the other
+	// tests.
+	counts := make(map[uint64]int)
+	for n := 0; n < 31*100; n++ {
+		counts[dictionary.Int64Key(n).Hash64()%31]++
+	}
+	for _, n := range counts {
+		require.InDelta(t, 100, n, 40, "poorly distributed hash")
+	}
+}
+
+func TestCompositeKey(t *testing.T) {
+	d := dictionary.New()
+
+	for _, tenant := range []string{"a", "b"} {
+		for n := 0; n < 10; n++ {
+			k := dictionary.NewCompositeKey(dictionary.StringKey(tenant), dictionary.Int64Key(n))
+			d.Set(k, tenant+strconv.Itoa(n))
+		}
+	}
+	require.Equal(t, 20, d.Len(), "unexpected length")
+
+	v, ok := d.Get(dictionary.CompositeKey{dictionary.StringKey("b"), dictionary.Int64Key(7)})
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "b7", v.(string), "unexpected value")
+
+	_, ok = d.Get(dictionary.CompositeKey{dictionary.StringKey("b")})
+	require.Equal(t, false, ok, "should not have found key")
+
+	ab := dictionary.CompositeKey{dictionary.StringKey("a"), dictionary.StringKey("b")}
+	ba := dictionary.CompositeKey{dictionary.StringKey("b"), dictionary.StringKey("a")}
+	require.NotEqual(t, ab.Hash64(), ba.Hash64(), "order of parts should matter")
+}
+
+func TestFoldedStringKey(t *testing.T) {
+	d := dictionary.New()
+
+	d.Set(dictionary.FoldedStringKey("Content-Type"), "text/plain")
+	d.Set(dictionary.FoldedStringKey("content-type"), "text/html")
+	require.Equal(t, 1, d.Len(), "unexpected length")
+
+	v, ok := d.Get(dictionary.FoldedStringKey("CONTENT-TYPE"))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "text/html", v.(string), "unexpected value")
+
+	// the Kelvin sign folds to k, and long s to s.
+	d.Set(dictionary.FoldedStringKey("\u212aelvin"), 1)
+	require.Equal(t, true, d.Contains(dictionary.FoldedStringKey("KELVIN")), "should have found key")
+	d.Set(dictionary.FoldedStringKey("\u017fs"), 2)
+	require.Equal(t, true, d.Contains(dictionary.FoldedStringKey("SS")), "should have found key")
+
+	require.Equal(t, true, dictionary.FoldedStringKey("apple").Less(dictionary.FoldedStringKey("Banana")))
+}
+
+func TestAddrKeys(t *testing.T) {
+	d := dictionary.New()
+
+	for _, s := range []string{"10.0.0.1", "10.0.0.2", "::ffff:10.0.0.1", "fe80::1%eth0", "fe80::1"} {
+		d.Set(dictionary.AddrKey(netip.MustParseAddr(s)), s)
+	}
+	require.Equal(t, 5, d.Len(), "unexpected length")
+
+	v, ok := d.Get(dictionary.AddrKey(netip.MustParseAddr("10.0.0.1")))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "10.0.0.1", v.(string), "unexpected value")
+
+	v, ok = d.Get(dictionary.AddrKey(netip.MustParseAddr("fe80::1%eth0")))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "fe80::1%eth0", v.(string), "unexpected value")
+
+	k, _, _ := d.Min()
+	require.Equal(t, "10.0.0.1", k.(dictionary.AddrKey).String())
+
+	p := dictionary.New()
+	p.Set(dictionary.AddrPortKey(netip.MustParseAddrPort("10.0.0.1:80")), "http")
+	p.Set(dictionary.AddrPortKey(netip.MustParseAddrPort("10.0.0.1:443")), "https")
+	require.Equal(t, 2, p.Len(), "unexpected length")
+
+	v, ok = p.Get(dictionary.AddrPortKey(netip.MustParseAddrPort("10.0.0.1:443")))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "https", v.(string), "unexpected value")
+
+	k, _, _ = p.Max()
+	require.Equal(t, "10.0.0.1:443", k.(dictionary.AddrPortKey).String())
+}
+
+func TestAutoKey(t *testing.T) {
+	type pair struct {
+		tenant string
+		id     int
+	}
+
+	d := dictionary.New()
+	d.Set(dictionary.AutoKey(pair{"a", 1}), "a1")
+	d.Set(dictionary.AutoKey(pair{"a", 2}), "a2")
+	d.Set(dictionary.AutoKey(pair{"a", 1}), "a1 again")
+	d.Set(dictionary.AutoKey(1), "int")
+	require.Equal(t, 3, d.Len(), "unexpected length")
+
+	v, ok := d.Get(dictionary.AutoKey(pair{"a", 1}))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "a1 again", v.(string), "unexpected value")
+
+	require.NoError(t, d.CheckInvariants())
+
+	// different types are never equal.
+	_, ok = d.Get(dictionary.AutoKey(int64(1)))
+	require.Equal(t, false, ok, "should not have found key")
+
+	for _, k := range d.Keys() {
+		require.NotNil(t, k.(dictionary.ComparableKey).Value)
+	}
+
+	require.Panics(t, func() { dictionary.AutoKey([]int{1}) })
+	require.Panics(t, func() { dictionary.AutoKey(nil) })
+}
+
+// countingKey counts how many times it is hashed.
+type countingKey struct {
+	n      int
+	hashes *int
+}
+
+func (k countingKey) Hash() uint32 {
+	*k.hashes++
+	return uint32(k.n)
+}
+
+func (k countingKey) Equal(v interface{}) bool {
+	return k.n == v.(countingKey).n
+}
+
+func TestCachedKey(t *testing.T) {
+	var hashes int
+	d := dictionary.New()
+	k := d.CachedKey(countingKey{n: 1, hashes: &hashes})
+	require.Equal(t, 1, hashes, "should hash once when created")
+
+	d.Set(k, "a")
+	d.Set(k, "b")
+	v, ok := d.Get(k)
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "b", v.(string), "unexpected value")
+	_, ok = d.Delete(k)
+	require.Equal(t, true, ok, "should have deleted key")
+	require.Equal(t, 1, hashes, "should not hash again")
+
+	// an equal key wrapped separately is found.
+	d.Set(k, "c")
+	v, ok = d.Get(d.CachedKey(countingKey{n: 1, hashes: &hashes}))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, "c", v.(string), "unexpected value")
+
+	// keys may be shared by dictionaries with the same seed, and are hashed
+	// again, without being changed, by dictionaries with another one.
+	seed := maphash.MakeSeed()
+	s := dictionary.CachedKey(dictionary.StringKey("foo"), seed)
+	d = dictionary.New(dictionary.SetHashSeed(seed))
+	d.Set(s, 1)
+	require.Equal(t, true, d.Contains(dictionary.CachedKey(dictionary.StringKey("foo"), seed)), "should have found key")
+	require.Equal(t, false, dictionary.New().Contains(s), "should not have found key")
+	o := dictionary.New()
+	o.Set(s, 2)
+	require.Equal(t, true, o.Contains(o.CachedKey(dictionary.StringKey("foo"))), "should have found key")
+	require.Equal(t, true, d.Contains(s), "should have found key")
+	require.Equal(t, dictionary.StringKey("foo"), d.Keys()[0].(*dictionary.CachedHasher).Key)
+}
+
+func TestKeyNormalizer(t *testing.T) {
+	normalize := func(k dictionary.Hasher) dictionary.Hasher {
+		if s, ok := k.(dictionary.StringKey); ok {
+			return dictionary.StringKey(strings.ToLower(strings.TrimSpace(string(s))))
+		}
+		return k
+	}
+	d := dictionary.New(dictionary.WithKeyNormalizer(normalize))
+
+	d.Set(dictionary.StringKey(" Foo "), 1)
+	d.Set(dictionary.StringKey("FOO"), 2)
+	require.Equal(t, 1, d.Len())
+	require.Equal(t, []dictionary.Hasher{dictionary.StringKey("foo")}, d.Keys())
+	v, ok := d.Get(dictionary.StringKey("fOo"))
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, 2, v.(int), "unexpected value")
+
+	f := d.Freeze()
+	require.Equal(t, true, f.Contains(dictionary.StringKey("Foo")), "should have found key")
+	require.Equal(t, true, d.Snapshot().Contains(dictionary.StringKey("Foo")), "should have found key")
+
+	// keys from a dictionary without the normalizer are normalized when
+	// merged in.
+	other := dictionary.New()
+	other.Set(dictionary.StringKey("BAR"), 3)
+	d.Merge(other, nil)
+	require.Equal(t, true, d.Contains(dictionary.StringKey("bar")), "should have found key")
+
+	_, ok = d.Delete(dictionary.StringKey("FOO "))
+	require.Equal(t, true, ok, "should have deleted key")
+	require.Equal(t, 1, d.Len())
+}
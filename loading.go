@@ -0,0 +1,119 @@
+package dictionary
+
+import "errors"
+
+// LoaderFunc loads the value for a key missing from a Loading dictionary.
+type LoaderFunc func(key Hasher) (interface{}, error)
+
+// Loading is a read-through cache: Get loads missing entries by calling a
+// loader, such as one that queries a database, and stores the result. Unlike
+// a dictionary with SetDefault, loading can fail, and the error is returned
+// to the caller. A Loading dictionary is safe for concurrent use. The loader
+// is called without holding the lock, so slow loads do not block lookups of
+// other keys, and concurrent calls to Get for the same missing key share a
+// single call to the loader.
+type Loading struct {
+	d      *Dictionary
+	loader LoaderFunc
+	// the loads in progress, by key.
+	loads *Dictionary
+}
+
+// loadCall is a call to the loader that other callers of Get can wait for.
+type loadCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// errLoaderPanicked is returned to callers waiting on a load that panicked.
+var errLoaderPanicked = errors.New("dictionary: loader panicked")
+
+// NewLoading creates a Loading dictionary that calls loader for missing keys.
+// Other options can be set by passing in OptionsFunc, such as SetMaxEntries to
+// bound the size of the cache.
+func NewLoading(loader LoaderFunc, options ...OptionsFunc) *Loading {
+	d := New(options...)
+	return &Loading{
+		d:      d,
+		loader: loader,
+		loads:  d.newLike(),
+	}
+}
+
+// Get returns the value for key, loading and storing it if it is not present.
+// If the loader fails, its error is returned and nothing is stored, so the
+// next Get tries again. If the key is already being loaded, Get waits for that
+// load and returns its result rather than calling the loader again. A loaded
+// value that an admission filter, such as WithTinyLFU, turns away is still
+// returned, but not stored.
+func (l *Loading) Get(key Hasher) (interface{}, error) {
+	l.d.Lock()
+	if v, ok := l.d.Get(key); ok {
+		l.d.Unlock()
+		return v, nil
+	}
+	if c, ok := l.loads.Get(key); ok {
+		l.d.Unlock()
+		c := c.(*loadCall)
+		<-c.done
+		return c.value, c.err
+	}
+	c := &loadCall{done: make(chan struct{}), err: errLoaderPanicked}
+	l.loads.Set(key, c)
+	l.d.Unlock()
+
+	defer func() {
+		l.d.Lock()
+		// a Set or Delete while loading replaces the loaded value, so it is
+		// only stored if the load is still current.
+		if cur, ok := l.loads.Get(key); ok && cur == c {
+			l.loads.Delete(key)
+			if c.err == nil {
+				l.d.Set(key, c.value)
+			}
+		}
+		l.d.Unlock()
+		close(c.done)
+	}()
+	c.value, c.err = l.loader(key)
+	if c.err != nil {
+		c.value = nil
+	}
+	return c.value, c.err
+}
+
+// Set adds an item to the dictionary, replacing any existing value, without
+// calling the loader.
+func (l *Loading) Set(key Hasher, val interface{}) {
+	l.d.Lock()
+	defer l.d.Unlock()
+	// a load in progress must not replace the value.
+	l.loads.Delete(key)
+	l.d.Set(key, val)
+}
+
+// Delete removes an item from the dictionary, so the next Get loads it again.
+// Returns the deleted value.
+func (l *Loading) Delete(key Hasher) (interface{}, bool) {
+	l.d.Lock()
+	defer l.d.Unlock()
+	// a load in progress must not store a value for the deleted key.
+	l.loads.Delete(key)
+	return l.d.Delete(key)
+}
+
+// Contains reports whether key is present in the dictionary, without loading
+// it.
+func (l *Loading) Contains(key Hasher) bool {
+	l.d.Lock()
+	defer l.d.Unlock()
+	return l.d.Contains(key)
+}
+
+// Len returns the number of entries in the dictionary.
+func (l *Loading) Len() int {
+	l.d.Lock()
+	defer l.d.Unlock()
+	return l.d.Len()
+}
@@ -0,0 +1,49 @@
+package dictionary
+
+import "container/list"
+
+// NewLRU creates a dictionary that holds at most maxEntries entries. When an
+// entry is added to a full dictionary, the least recently used entry is
+// evicted. Setting or getting an entry counts as using it. Other options can
+// be set by passing in OptionsFunc.
+func NewLRU(maxEntries int, options ...OptionsFunc) *Dictionary {
+	return New(append([]OptionsFunc{SetMaxEntries(maxEntries), SetEvictionPolicy(NewLRUPolicy())}, options...)...)
+}
+
+// lruPolicy evicts the least recently used entry.
+type lruPolicy struct {
+	// keys ordered from most to least recently used.
+	recency *list.List
+	// element in recency for each key.
+	index *Dictionary
+}
+
+// NewLRUPolicy returns an EvictionPolicy that evicts the least recently used
+// entry.
+func NewLRUPolicy() EvictionPolicy {
+	return &lruPolicy{
+		recency: list.New(),
+		index:   newPolicyIndex(),
+	}
+}
+
+func (p *lruPolicy) Touch(key Hasher) {
+	p.index.Update(key, func(old interface{}, exists bool) (interface{}, bool) {
+		if exists {
+			e := old.(*list.Element)
+			p.recency.MoveToFront(e)
+			return e, false
+		}
+		return p.recency.PushFront(key), false
+	})
+}
+
+func (p *lruPolicy) Remove(key Hasher) {
+	if e, ok := p.index.Delete(key); ok {
+		p.recency.Remove(e.(*list.Element))
+	}
+}
+
+func (p *lruPolicy) Evictee() Hasher {
+	return p.recency.Back().Value.(Hasher)
+}
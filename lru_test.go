@@ -0,0 +1,242 @@
+package dictionary_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bakins/dictionary"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	d := dictionary.NewLRU(2)
+	a := dictionary.StringKey("a")
+	b := dictionary.StringKey("b")
+	c := dictionary.StringKey("c")
+
+	d.Set(a, 1)
+	d.Set(b, 2)
+
+	// a is now the most recently used, so b is evicted.
+	_, ok := d.Get(a)
+	require.Equal(t, true, ok, "should have found key")
+	d.Set(c, 3)
+
+	require.Equal(t, 2, d.Len(), "unexpected length")
+	require.Equal(t, true, d.Contains(a), "should have found key")
+	require.Equal(t, false, d.Contains(b), "should have evicted key")
+	require.Equal(t, true, d.Contains(c), "should have found key")
+
+	// deleting makes room without evicting.
+	d.Delete(a)
+	d.Set(b, 2)
+	require.Equal(t, 2, d.Len(), "unexpected length")
+	require.Equal(t, true, d.Contains(c), "should have found key")
+}
+
+func TestRandomPolicy(t *testing.T) {
+	d := dictionary.New(
+		dictionary.SetMaxEntries(10),
+		dictionary.SetEvictionPolicy(dictionary.NewRandomPolicy()),
+	)
+
+	for i := 0; i < 100; i++ {
+		d.Set(intKey(i), i)
+		require.Equal(t, true, d.Len() <= 10, "should not exceed max entries")
+	}
+	require.Equal(t, 10, d.Len(), "unexpected length")
+
+	for _, k := range d.Keys() {
+		d.Delete(k)
+	}
+	require.Equal(t, 0, d.Len(), "unexpected length")
+
+	for i := 0; i < 10; i++ {
+		d.Set(intKey(i), i)
+	}
+	require.Equal(t, 10, d.Len(), "should not have evicted")
+}
+
+// stalePolicy always chooses a key that is not in the dictionary.
+type stalePolicy struct {
+	removed int
+}
+
+func (p *stalePolicy) Touch(dictionary.Hasher) {}
+
+func (p *stalePolicy) Remove(dictionary.Hasher) {
+	p.removed++
+}
+
+func (p *stalePolicy) Evictee() dictionary.Hasher {
+	return dictionary.StringKey("missing")
+}
+
+func TestEvictMissingKey(t *testing.T) {
+	p := &stalePolicy{}
+	d := dictionary.New(dictionary.SetMaxEntries(1), dictionary.SetEvictionPolicy(p))
+	d.Set(intKey(1), 1)
+	d.Set(intKey(2), 2)
+	require.Equal(t, 2, d.Len(), "nothing should have been evicted")
+	require.Equal(t, 1, p.removed, "policy should have been told to forget the key")
+}
+
+func TestMaxEntriesDefaultsToLRU(t *testing.T) {
+	d := dictionary.New(dictionary.SetMaxEntries(1))
+	d.Set(intKey(1), 1)
+	d.Set(intKey(2), 2)
+	require.Equal(t, false, d.Contains(intKey(1)), "should have evicted key")
+	require.Equal(t, true, d.Contains(intKey(2)), "should have found key")
+}
+
+func TestMaxWeight(t *testing.T) {
+	d := dictionary.New(
+		dictionary.WithWeigher(func(k dictionary.Hasher, v interface{}) int {
+			return len(v.([]byte))
+		}),
+		dictionary.SetMaxWeight(10),
+	)
+
+	d.Set(intKey(1), make([]byte, 4))
+	d.Set(intKey(2), make([]byte, 4))
+	require.Equal(t, 2, d.Len(), "unexpected length")
+
+	// 1 is the least recently used.
+	d.Set(intKey(3), make([]byte, 4))
+	require.Equal(t, false, d.Contains(intKey(1)), "should have evicted key")
+	require.Equal(t, 2, d.Len(), "unexpected length")
+
+	// growing an entry evicts others.
+	d.Set(intKey(3), make([]byte, 9))
+	require.Equal(t, false, d.Contains(intKey(2)), "should have evicted key")
+	require.Equal(t, true, d.Contains(intKey(3)), "should have found key")
+
+	// entries that are too heavy are not kept.
+	d.Set(intKey(4), make([]byte, 11))
+	require.Equal(t, 0, d.Len(), "unexpected length")
+}
+
+func TestTinyLFU(t *testing.T) {
+	hits := make(map[string]int)
+	for name, options := range map[string][]dictionary.OptionsFunc{
+		"lru":     nil,
+		"tinylfu": {dictionary.WithTinyLFU()},
+	} {
+		d := dictionary.NewLRU(10, options...)
+		for n := 0; n < 10; n++ {
+			d.Set(intKey(n), n)
+		}
+
+		// popular keys are used between a scan of keys that are each
+		// only seen once.
+		for n := 100; n < 1100; n++ {
+			d.Set(intKey(n), n)
+			k := intKey(n % 10)
+			if _, ok := d.Get(k); ok {
+				hits[name]++
+			} else {
+				d.Set(k, n)
+			}
+		}
+		require.Equal(t, 10, d.Len(), "unexpected length")
+	}
+	require.True(t, hits["lru"] < 100, "lru should have evicted popular keys, got %d hits", hits["lru"])
+	require.True(t, hits["tinylfu"] > 900, "popular keys should not be evicted by a scan, got %d hits", hits["tinylfu"])
+}
+
+func TestOnEvict(t *testing.T) {
+	type eviction struct {
+		key    dictionary.Hasher
+		val    interface{}
+		reason dictionary.EvictReason
+	}
+	var evictions []eviction
+	onEvict := dictionary.WithOnEvict(func(key dictionary.Hasher, val interface{}, reason dictionary.EvictReason) {
+		evictions = append(evictions, eviction{key, val, reason})
+	})
+	a := dictionary.StringKey("a")
+	b := dictionary.StringKey("b")
+	c := dictionary.StringKey("c")
+
+	d := dictionary.New(dictionary.SetMaxEntries(2), onEvict)
+	d.Set(a, 1)
+	d.Set(b, 2)
+	d.Delete(b)
+	require.Equal(t, 0, len(evictions), "deleting should not evict")
+	d.Set(b, 2)
+	d.Set(c, 3)
+	require.Equal(t, []eviction{{a, 1, dictionary.EvictCapacity}}, evictions)
+
+	evictions = nil
+	clock := newFakeClock()
+	d = dictionary.New(onEvict, dictionary.WithClock(clock.Now))
+	d.SetWithTTL(a, 1, time.Millisecond)
+	clock.Advance(time.Millisecond)
+	require.Equal(t, false, d.Contains(a), "key should have expired")
+	require.Equal(t, []eviction{{a, 1, dictionary.EvictExpired}}, evictions)
+
+	// a is used far more often than b, so b is turned away.
+	evictions = nil
+	d = dictionary.New(dictionary.SetMaxEntries(1), dictionary.WithTinyLFU(), onEvict)
+	d.Set(a, 1)
+	for n := 0; n < 5; n++ {
+		d.Get(a)
+	}
+	d.Set(b, 2)
+	require.Equal(t, []eviction{{b, 2, dictionary.EvictRejected}}, evictions)
+}
+
+func TestTinyLFURejected(t *testing.T) {
+	a := dictionary.StringKey("a")
+	b := dictionary.StringKey("b")
+	// a is used far more often than b, so every way of adding b to the full
+	// dictionary is turned away, and says so.
+	newFull := func(options ...dictionary.OptionsFunc) *dictionary.Dictionary {
+		d := dictionary.New(append([]dictionary.OptionsFunc{dictionary.SetMaxEntries(1), dictionary.WithTinyLFU()}, options...)...)
+		d.Set(a, 1)
+		for n := 0; n < 5; n++ {
+			d.Get(a)
+		}
+		return d
+	}
+
+	d := newFull()
+	require.Equal(t, false, d.TrySet(b, 2), "set should have been rejected")
+	require.Equal(t, false, d.Contains(b), "should not have found key")
+	d.SetWithTTL(b, 2, time.Hour)
+	require.Equal(t, false, d.Contains(b), "should not have found key")
+	require.Equal(t, false, d.SetIfAbsent(b, 2), "set should have been rejected")
+	v, loaded := d.GetOrSet(b, 2)
+	require.Equal(t, 2, v, "unexpected value")
+	require.Equal(t, false, loaded, "should not have been present")
+	v, ok := d.Update(b, func(interface{}, bool) (interface{}, bool) { return 2, false })
+	require.Equal(t, false, ok, "update should have been rejected")
+	require.Nil(t, v, "unexpected value")
+	require.Equal(t, false, d.Contains(b), "should not have found key")
+	require.Equal(t, true, d.TrySet(a, 3), "replacing should be stored")
+
+	d = newFull(dictionary.SetDefault(func(dictionary.Hasher) interface{} { return 2 }))
+	v, ok = d.Get(b)
+	require.Equal(t, 2, v, "unexpected value")
+	require.Equal(t, false, ok, "created entry should have been rejected")
+	require.Equal(t, false, d.Contains(b), "should not have found key")
+
+	c := dictionary.NewCounter(dictionary.SetMaxEntries(1), dictionary.WithTinyLFU())
+	for n := 0; n < 5; n++ {
+		c.Incr(a)
+	}
+	require.Equal(t, 0, c.Incr(b), "incr should have been rejected")
+	require.Equal(t, 0, c.Count(b), "unexpected count")
+
+	l := dictionary.NewLoading(func(dictionary.Hasher) (interface{}, error) { return 2, nil }, dictionary.SetMaxEntries(1), dictionary.WithTinyLFU())
+	l.Set(a, 1)
+	for n := 0; n < 5; n++ {
+		l.Get(a)
+	}
+	v, err := l.Get(b)
+	require.Nil(t, err)
+	require.Equal(t, 2, v, "loaded value should be returned")
+	require.Equal(t, false, l.Contains(b), "should not have stored loaded value")
+	l.Set(b, 3)
+	require.Equal(t, false, l.Contains(b), "should not have stored value")
+}
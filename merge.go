@@ -0,0 +1,147 @@
+package dictionary
+
+import "reflect"
+
+// Merge adds all of the entries in other to the dictionary. For keys present
+// in both, resolve is called to pick the value to keep. If resolve is nil, the
+// value from other is used.
+func (d *Dictionary) Merge(other *Dictionary, resolve MergeFunc) {
+	// Each only returns the errors our function returns.
+	_ = other.Each(func(key Hasher, b interface{}) error {
+		d.Update(key, func(a interface{}, exists bool) (interface{}, bool) {
+			if !exists || resolve == nil {
+				return b, false
+			}
+			return resolve(key, a, b), false
+		})
+		return nil
+	})
+}
+
+// helper to create an empty dictionary with the same settings as d. It
+// shares the hash seed, so hashes can be copied between them.
+func (d *Dictionary) newLike() *Dictionary {
+	return New(SetBuckets(d.numBuckets), SetHashSeed(d.seed), WithStringHash(d.stringHash), WithKeyNormalizer(d.normalizer), WithBackend(d.backend), withClock(d.clock))
+}
+
+// helper to report whether keys and hashes stored in from can be used in d.
+func (d *Dictionary) sameHashing(from *Dictionary) bool {
+	// functions cannot be compared, so assume different string hashes differ,
+	// and that keys from other dictionaries need normalizing.
+	return d == from || (d.seed == from.seed && d.stringHash == nil && from.stringHash == nil && d.normalizer == nil)
+}
+
+// helper to find the item matching an item from another dictionary. The stored
+// key and hash are reused if both dictionaries hash keys the same way.
+func (d *Dictionary) findItem(from *Dictionary, i *item) *item {
+	if !d.sameHashing(from) {
+		_, _, found := d.lookup(i.key)
+		return found
+	}
+	return d.find(i.key, i.hash)
+}
+
+// helper to add a copy of an item from another dictionary that is known not
+// to be present.
+func (d *Dictionary) addItem(from *Dictionary, i *item) {
+	key, h := i.key, i.hash
+	if !d.sameHashing(from) {
+		key = d.normalize(key)
+		h = d.hash(key)
+	}
+	c := d.newItem(key, h, i.value)
+	c.expires = i.expires
+	c.ttl = i.ttl
+	d.add(c)
+}
+
+// helper to copy the entries of d for which keep returns true into a new
+// dictionary. The stored hashes are reused rather than hashing every key again.
+func (d *Dictionary) filter(keep func(i *item) bool) *Dictionary {
+	out := d.newLike()
+	d.walk(func(i *item) bool {
+		if keep(i) {
+			out.addItem(d, i)
+		}
+		return true
+	})
+	return out
+}
+
+// Union returns a new dictionary containing the entries of both dictionaries.
+// For keys present in both, the value from d is used.
+func (d *Dictionary) Union(other *Dictionary) *Dictionary {
+	out := d.filter(func(*item) bool { return true })
+	other.walk(func(i *item) bool {
+		if out.findItem(other, i) == nil {
+			out.addItem(other, i)
+		}
+		return true
+	})
+	return out
+}
+
+// Intersect returns a new dictionary containing the entries of d whose keys are
+// also present in other.
+func (d *Dictionary) Intersect(other *Dictionary) *Dictionary {
+	return d.filter(func(i *item) bool {
+		return other.findItem(d, i) != nil
+	})
+}
+
+// Difference returns a new dictionary containing the entries of d whose keys are
+// not present in other.
+func (d *Dictionary) Difference(other *Dictionary) *Dictionary {
+	return d.filter(func(i *item) bool {
+		return other.findItem(d, i) == nil
+	})
+}
+
+// Diff compares d, as the old version, with other, as the new version. It
+// returns the keys only present in d, the keys only present in other, and the
+// keys present in both whose values are not equal according to eq. If eq is
+// nil, values are compared using reflect.DeepEqual.
+func (d *Dictionary) Diff(other *Dictionary, eq EqualFunc) (removed, added, changed []Hasher) {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+
+	d.walk(func(i *item) bool {
+		o := other.findItem(d, i)
+		switch {
+		case o == nil:
+			removed = append(removed, i.key)
+		case !eq(i.value, o.value):
+			changed = append(changed, i.key)
+		}
+		return true
+	})
+
+	other.walk(func(i *item) bool {
+		if d.findItem(other, i) == nil {
+			added = append(added, i.key)
+		}
+		return true
+	})
+
+	return removed, added, changed
+}
+
+// Equal reports whether d and other contain the same keys with equal values
+// according to eq. If eq is nil, values are compared using reflect.DeepEqual.
+func (d *Dictionary) Equal(other *Dictionary, eq EqualFunc) bool {
+	if d.Len() != other.Len() {
+		return false
+	}
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+
+	equal := true
+	d.walk(func(i *item) bool {
+		o := other.findItem(d, i)
+		equal = o != nil && eq(i.value, o.value)
+		return equal
+	})
+	return equal
+}
@@ -0,0 +1,88 @@
+package dictionary
+
+import "reflect"
+
+// MultiDict holds several values per key, like url.Values. It is built on a
+// Dictionary with slices of values.
+type MultiDict struct {
+	d *Dictionary
+}
+
+// NewMultiDict creates an empty MultiDict. Options are passed to New for the
+// underlying dictionary.
+func NewMultiDict(options ...OptionsFunc) *MultiDict {
+	return &MultiDict{d: New(options...)}
+}
+
+// Add appends val to the values for key.
+func (m *MultiDict) Add(key Hasher, val interface{}) {
+	m.d.Update(key, func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return []interface{}{val}, false
+		}
+		return append(old.([]interface{}), val), false
+	})
+}
+
+// Get returns the values for key, in the order they were added. It returns
+// nil if the key is not present. The returned slice must not be modified.
+func (m *MultiDict) Get(key Hasher) []interface{} {
+	if v, ok := m.d.Get(key); ok {
+		return v.([]interface{})
+	}
+	return nil
+}
+
+// Delete removes all of the values for key.
+func (m *MultiDict) Delete(key Hasher) {
+	m.d.Delete(key)
+}
+
+// DeleteValue removes the first value for key that is equal to val, as
+// compared by reflect.DeepEqual. The key is removed along with its last value.
+// It returns true if a value was removed.
+func (m *MultiDict) DeleteValue(key Hasher, val interface{}) bool {
+	removed := false
+	m.d.Update(key, func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return nil, true
+		}
+		values := old.([]interface{})
+		for n, v := range values {
+			if reflect.DeepEqual(v, val) {
+				removed = true
+				// copy, as callers may still hold the slice returned by Get.
+				values = append(append([]interface{}{}, values[:n]...), values[n+1:]...)
+				break
+			}
+		}
+		return values, len(values) == 0
+	})
+	return removed
+}
+
+// Len returns the number of keys.
+func (m *MultiDict) Len() int {
+	return m.d.Len()
+}
+
+// Keys returns all of the keys.
+func (m *MultiDict) Keys() []Hasher {
+	return m.d.Keys()
+}
+
+// GroupBy groups items into a MultiDict by the key keyFn returns for each.
+// The values for a key are the results of valFn, in the order of items, or the
+// items themselves if valFn is nil. Options are passed to New for the
+// underlying dictionary.
+func GroupBy[T any](items []T, keyFn func(T) Hasher, valFn func(T) interface{}, options ...OptionsFunc) *MultiDict {
+	m := NewMultiDict(options...)
+	for _, item := range items {
+		var val interface{} = item
+		if valFn != nil {
+			val = valFn(item)
+		}
+		m.Add(keyFn(item), val)
+	}
+	return m
+}
@@ -0,0 +1,25 @@
+package dictionary
+
+// NormalizeFunc converts a key to its canonical form, such as by trimming and
+// lowercasing a string, so keys that differ only in ways that do not matter
+// find the same entry. It must be idempotent: normalizing an already
+// normalized key must return an equal key.
+type NormalizeFunc func(Hasher) Hasher
+
+// WithKeyNormalizer sets a function applied to every key passed to the
+// dictionary, such as by Set, Get and Delete, before it is hashed. Entries are
+// stored under the normalized key, so that is what Each and Keys return.
+func WithKeyNormalizer(f NormalizeFunc) OptionsFunc {
+	return func(d *Dictionary) {
+		d.normalizer = f
+	}
+}
+
+// helper to normalize a key, if there is a normalizer. A nil key is left
+// alone, so hashing it still panics with ErrNilKey.
+func (d *Dictionary) normalize(key Hasher) Hasher {
+	if d.normalizer == nil || key == nil {
+		return key
+	}
+	return d.normalizer(key)
+}
@@ -0,0 +1,34 @@
+package dictionary
+
+// Add adds delta, which may be negative, to the int64 value for key and
+// returns the new value. Missing keys start at zero. The key is only hashed
+// and looked up once, so when used with Lock and Unlock, concurrent calls
+// never lose an update. It panics if the existing value is not an int64. If an
+// admission filter, such as WithTinyLFU, turns a new key away, the value stays
+// at zero.
+func (d *Dictionary) Add(key Hasher, delta int64) int64 {
+	v, ok := d.Update(key, func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return delta, false
+		}
+		return old.(int64) + delta, false
+	})
+	if !ok {
+		return 0
+	}
+	return v.(int64)
+}
+
+// AddFloat is like Add, but for float64 values.
+func (d *Dictionary) AddFloat(key Hasher, delta float64) float64 {
+	v, ok := d.Update(key, func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return delta, false
+		}
+		return old.(float64) + delta, false
+	})
+	if !ok {
+		return 0
+	}
+	return v.(float64)
+}
@@ -0,0 +1,99 @@
+package dictionary
+
+import "math"
+
+// mix is the finalizer from splitmix64. It spreads sequential values, such as
+// IDs, evenly across all of the bits.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// fold reduces a 64 bit hash to 32 bits for Hash.
+func fold(h uint64) uint32 {
+	return uint32(h ^ h>>32)
+}
+
+// Int64Key is a convenience type for using integers as keys in a dictionary.
+type Int64Key int64
+
+// Hash generates a hash for the integer. See Hash64.
+func (i Int64Key) Hash() uint32 {
+	return fold(i.Hash64())
+}
+
+// Hash64 generates a hash for the integer using the splitmix64 finalizer, so
+// sequential integers are spread across buckets.
+func (i Int64Key) Hash64() uint64 {
+	return mix(uint64(i))
+}
+
+// Equal reports whether v, which must also be an Int64Key, is the same integer.
+func (i Int64Key) Equal(v interface{}) bool {
+	return i == v.(Int64Key)
+}
+
+// Less reports whether i is less than v, which must also be an Int64Key.
+func (i Int64Key) Less(v Hasher) bool {
+	return i < v.(Int64Key)
+}
+
+// Uint64Key is a convenience type for using unsigned integers as keys in a
+// dictionary.
+type Uint64Key uint64
+
+// Hash generates a hash for the integer. See Hash64.
+func (u Uint64Key) Hash() uint32 {
+	return fold(u.Hash64())
+}
+
+// Hash64 generates a hash for the integer using the splitmix64 finalizer, so
+// sequential integers are spread across buckets.
+func (u Uint64Key) Hash64() uint64 {
+	return mix(uint64(u))
+}
+
+// Equal reports whether v, which must also be a Uint64Key, is the same integer.
+func (u Uint64Key) Equal(v interface{}) bool {
+	return u == v.(Uint64Key)
+}
+
+// Less reports whether u is less than v, which must also be a Uint64Key.
+func (u Uint64Key) Less(v Hasher) bool {
+	return u < v.(Uint64Key)
+}
+
+// Float64Key is a convenience type for using floating point numbers as keys
+// in a dictionary. Keys are compared with ==, so NaN can be added but never
+// found again, as with built-in maps.
+type Float64Key float64
+
+// Hash generates a hash for the number. See Hash64.
+func (f Float64Key) Hash() uint32 {
+	return fold(f.Hash64())
+}
+
+// Hash64 generates a hash of the bits of the number using the splitmix64
+// finalizer.
+func (f Float64Key) Hash64() uint64 {
+	if f == 0 {
+		// 0 and -0 are equal, so must have the same hash.
+		return mix(0)
+	}
+	return mix(math.Float64bits(float64(f)))
+}
+
+// Equal reports whether v, which must also be a Float64Key, is the same
+// number.
+func (f Float64Key) Equal(v interface{}) bool {
+	return f == v.(Float64Key)
+}
+
+// Less reports whether f is less than v, which must also be a Float64Key.
+func (f Float64Key) Less(v Hasher) bool {
+	return f < v.(Float64Key)
+}
@@ -0,0 +1,103 @@
+package dictionary
+
+// Observer is notified of operations on a dictionary, such as for recording
+// metrics. Its methods are called synchronously, while the dictionary is being
+// changed, so they must not use the dictionary. Embed NopObserver to only
+// implement some of them.
+type Observer interface {
+	// Hit is called when Get or Contains finds key.
+	Hit(key Hasher)
+	// Miss is called when Get or Contains does not find key.
+	Miss(key Hasher)
+	// Set is called when key is added or its value is replaced.
+	Set(key Hasher)
+	// Delete is called when key is removed, other than by eviction or
+	// expiration.
+	Delete(key Hasher)
+	// Evict is called when key is removed to keep the dictionary within
+	// its bounds.
+	Evict(key Hasher)
+	// Expire is called when key is removed because it has expired.
+	Expire(key Hasher)
+	// Rehash is called when the entries are moved to a new table, with the
+	// number of entries it can hold.
+	Rehash(capacity int)
+}
+
+// NopObserver is an Observer that does nothing. It can be embedded to
+// implement only some of the methods of Observer.
+type NopObserver struct{}
+
+// Hit does nothing.
+func (NopObserver) Hit(Hasher) {}
+
+// Miss does nothing.
+func (NopObserver) Miss(Hasher) {}
+
+// Set does nothing.
+func (NopObserver) Set(Hasher) {}
+
+// Delete does nothing.
+func (NopObserver) Delete(Hasher) {}
+
+// Evict does nothing.
+func (NopObserver) Evict(Hasher) {}
+
+// Expire does nothing.
+func (NopObserver) Expire(Hasher) {}
+
+// Rehash does nothing.
+func (NopObserver) Rehash(int) {}
+
+// WithInstrumentation sets an Observer to be notified of operations on the
+// dictionary.
+func WithInstrumentation(o Observer) OptionsFunc {
+	return func(d *Dictionary) {
+		d.observer = o
+	}
+}
+
+// helper to record a lookup that found key.
+func (d *Dictionary) hit(key Hasher) {
+	d.hits++
+	if d.observer != nil {
+		d.observer.Hit(key)
+	}
+}
+
+// helper to record a lookup that did not find key.
+func (d *Dictionary) miss(key Hasher) {
+	d.misses++
+	if d.observer != nil {
+		d.observer.Miss(key)
+	}
+}
+
+// helper to remove an item that was deleted by the user.
+func (d *Dictionary) delete(i *item) {
+	if d.observer != nil {
+		d.observer.Delete(i.key)
+	}
+	d.remove(i)
+}
+
+// helper to remove an item that has expired.
+func (d *Dictionary) expire(i *item) {
+	if d.observer != nil {
+		d.observer.Expire(i.key)
+	}
+	d.evicted(i, EvictExpired)
+}
+
+// helper to remove an item the dictionary chose to remove, and tell the
+// eviction hook.
+func (d *Dictionary) evicted(i *item, reason EvictReason) {
+	if d.onEvict == nil {
+		d.remove(i)
+		return
+	}
+	// the item may be reused once it is removed.
+	key, val := i.key, i.value
+	d.remove(i)
+	d.onEvict(key, val, reason)
+}
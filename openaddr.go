@@ -0,0 +1,172 @@
+package dictionary
+
+// openTable is an open addressing store. Items are kept in a single slice
+// whose length is a power of two. On a collision, the following slots are
+// probed with increasing steps (quadratic probing), which visits every slot
+// of the table. Deleted slots are marked with a tombstone rather than emptied,
+// so probe sequences that pass through them are not broken.
+type openTable struct {
+	slots []openSlot
+	// live items and tombstones, used to decide when to grow.
+	used  int
+	count int
+}
+
+type openSlot struct {
+	// kept alongside the item, so most mismatches are found without
+	// following the pointer.
+	hash uint64
+	item *item
+	// set when the item has been deleted.
+	deleted bool
+}
+
+// the smallest table we create.
+const minOpenSlots = 8
+
+func newOpenTable(n uint32) *openTable {
+	size := minOpenSlots
+	for size < int(n) {
+		size <<= 1
+	}
+	return &openTable{
+		slots: make([]openSlot, size),
+	}
+}
+
+// probe returns the slot for the i'th step of the probe sequence for h.
+func (t *openTable) probe(h uint64, i int) int {
+	mask := uint64(len(t.slots) - 1)
+	// keys with a poor Hash, such as sequential integers, would otherwise
+	// fill runs of neighbouring slots.
+	start := mix(h)
+	return int((start + uint64(i*(i+1)/2)) & mask)
+}
+
+func (t *openTable) find(key Hasher, h uint64) *item {
+	for i := 0; i < len(t.slots); i++ {
+		s := &t.slots[t.probe(h, i)]
+		switch {
+		case s.item == nil && !s.deleted:
+			return nil
+		case s.item != nil && s.hash == h && key.Equal(s.item.key):
+			return s.item
+		}
+	}
+	return nil
+}
+
+func (t *openTable) insert(i *item) {
+	// keep the table at most 3/4 full, counting tombstones, so probe
+	// sequences stay short.
+	if (t.used+1)*4 > len(t.slots)*3 {
+		t.resize()
+	}
+	for n := 0; ; n++ {
+		s := &t.slots[t.probe(i.hash, n)]
+		if s.item == nil {
+			if !s.deleted {
+				t.used++
+			}
+			*s = openSlot{hash: i.hash, item: i}
+			t.count++
+			return
+		}
+	}
+}
+
+func (t *openTable) delete(i *item) bool {
+	for n := 0; n < len(t.slots); n++ {
+		s := &t.slots[t.probe(i.hash, n)]
+		switch {
+		case s.item == nil && !s.deleted:
+			return false
+		case s.item == i:
+			*s = openSlot{deleted: true}
+			t.count--
+			return true
+		}
+	}
+	return false
+}
+
+// resize rehashes the items into a new table. The table only doubles if it
+// is at least half full of live items; otherwise, clearing the tombstones is
+// enough.
+func (t *openTable) resize() {
+	old := t.slots
+	size := len(old)
+	if t.count*2 >= size {
+		size <<= 1
+	}
+	t.slots = make([]openSlot, size)
+	t.used = 0
+	t.count = 0
+	for _, s := range old {
+		if s.item != nil {
+			t.insert(s.item)
+		}
+	}
+}
+
+func (t *openTable) capacity() int {
+	return len(t.slots) * 3 / 4
+}
+
+func (t *openTable) stats(s *Stats) {
+	s.Buckets += len(t.slots)
+	for _, slot := range t.slots {
+		if slot.item == nil {
+			s.EmptyBuckets++
+			continue
+		}
+		for n := 0; n < len(t.slots); n++ {
+			if t.slots[t.probe(slot.hash, n)].item == slot.item {
+				s.record(n + 1)
+				break
+			}
+		}
+	}
+}
+
+func (t *openTable) chain(i *item) (int, int) {
+	for n := 0; n < len(t.slots); n++ {
+		if t.slots[t.probe(i.hash, n)].item == i {
+			return t.probe(i.hash, 0), n + 1
+		}
+	}
+	return t.probe(i.hash, 0), 0
+}
+
+func (t *openTable) layout(f func(items []*item)) {
+	for _, s := range t.slots {
+		if s.item == nil {
+			f(nil)
+		} else {
+			f([]*item{s.item})
+		}
+	}
+}
+
+func (t *openTable) cursor() cursor {
+	return &openCursor{t: t}
+}
+
+type openCursor struct {
+	t *openTable
+	// the next slot to look at.
+	pos int
+}
+
+// deleting an item only leaves a tombstone, so the remaining slots are not
+// disturbed.
+func (c *openCursor) next() *item {
+	for c.pos < len(c.t.slots) {
+		s := c.t.slots[c.pos]
+		c.pos++
+		if s.item != nil {
+			return s.item
+		}
+	}
+	return nil
+}
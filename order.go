@@ -0,0 +1,103 @@
+package dictionary
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+)
+
+// Order is the order Each, EachContext, EachSafe and Keys visit entries in.
+type Order int
+
+const (
+	// BucketOrder visits entries in the order they are stored, which depends
+	// on the hash seed, the backend and the history of the dictionary. This is
+	// the default, and the fastest, as the entries do not need to be
+	// collected first.
+	BucketOrder Order = iota
+	// DeterministicOrder visits entries in the same order on every run, for
+	// reproducible tests and output. Keys are sorted if they all implement
+	// Ordered and are of the same type, and by Hash otherwise, so the order of
+	// keys with the same Hash is unspecified. AutoKey and AddrKey hash with a
+	// seed chosen when the program starts, so their order is only the same
+	// within a run.
+	DeterministicOrder
+	// RandomOrder visits entries in a different random order each time, like
+	// Go maps, to catch code that depends on the order.
+	RandomOrder
+	// InsertionOrder visits entries in the order they were added, oldest
+	// first, as Python dicts do. Replacing a value does not change the
+	// order. It also enables Oldest, Newest, PopOldest and MoveToBack.
+	InsertionOrder
+)
+
+// WithOrder sets the order Each, EachContext, EachSafe and Keys visit entries
+// in. Other methods, such as Find and Reduce, always use BucketOrder.
+func WithOrder(o Order) OptionsFunc {
+	return func(d *Dictionary) {
+		d.order = o
+	}
+}
+
+// helper to call f on each item, in the dictionary's order, until it returns
+// false. As with walk, f may remove the item it is passed.
+func (d *Dictionary) iterate(f func(i *item) bool) {
+	switch d.order {
+	case BucketOrder:
+		d.walk(f)
+		return
+	case InsertionOrder:
+		d.walkInserted(f)
+		return
+	}
+
+	var items []*item
+	var keys keyTypes
+	d.walk(func(i *item) bool {
+		keys.add(i.key)
+		items = append(items, i)
+		return true
+	})
+
+	switch {
+	case d.order == RandomOrder:
+		rand.Shuffle(len(items), func(a, b int) {
+			items[a], items[b] = items[b], items[a]
+		})
+	case keys.ordered():
+		sort.Slice(items, func(a, b int) bool {
+			return items[a].key.(Ordered).Less(items[b].key)
+		})
+	default:
+		sort.Slice(items, func(a, b int) bool {
+			return items[a].key.Hash() < items[b].key.Hash()
+		})
+	}
+
+	for _, i := range items {
+		if !f(i) {
+			return
+		}
+	}
+}
+
+// keyTypes tracks whether a set of keys can be sorted with Less. They must all
+// implement Ordered, and be of the same type, as Less may assume the key it is
+// passed is of its own type.
+type keyTypes struct {
+	typ   reflect.Type
+	mixed bool
+}
+
+func (k *keyTypes) add(key Hasher) {
+	t := reflect.TypeOf(key)
+	if _, ok := key.(Ordered); !ok || (k.typ != nil && t != k.typ) {
+		k.mixed = true
+	}
+	k.typ = t
+}
+
+// ordered reports whether the keys added can be sorted with Less.
+func (k *keyTypes) ordered() bool {
+	return !k.mixed
+}
@@ -0,0 +1,61 @@
+package dictionary
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// EachParallel executes the function on each element, like Each, using up to
+// workers goroutines. The entries are split, in bucket order, into one
+// contiguous run per worker. If workers is zero or less, GOMAXPROCS is used.
+//
+// f is called concurrently, so it must be safe for concurrent use, and it must
+// not modify the dictionary. A worker stops at the first error f returns, while
+// the others carry on with their entries; the errors from all of the workers
+// are joined and returned.
+func (d *Dictionary) EachParallel(workers int, f EachFunc) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// the entries are collected first, so expired ones are removed before
+	// any goroutines are started.
+	items := make([]*item, 0, d.Len())
+	d.walk(func(i *item) bool {
+		items = append(items, i)
+		return true
+	})
+	if workers > len(items) {
+		workers = len(items)
+	}
+	if workers <= 1 {
+		for _, i := range items {
+			if err := f(i.key, i.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for w := 0; w < workers; w++ {
+		// spread the remainder across the workers, so that no run is empty
+		// or starts past the end of items.
+		start := w * len(items) / workers
+		end := (w + 1) * len(items) / workers
+		wg.Add(1)
+		go func(w int, items []*item) {
+			defer wg.Done()
+			for _, i := range items {
+				if err := f(i.key, i.value); err != nil {
+					errs[w] = err
+					return
+				}
+			}
+		}(w, items[start:end])
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,78 @@
+package dictionary
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrEmptyPath is returned when setting an empty path.
+	ErrEmptyPath = errors.New("dictionary: empty path")
+	// ErrNotDictionary is returned when a path goes through a value that is
+	// not a dictionary.
+	ErrNotDictionary = errors.New("dictionary: path element is not a dictionary")
+)
+
+// SetPath sets the value at a path of keys through nested dictionaries, such
+// as for hierarchical configuration. Missing intermediate dictionaries are
+// created with the same number of buckets as d. ErrNotDictionary is returned if
+// an existing value along the path is not a *Dictionary.
+func (d *Dictionary) SetPath(path []Hasher, val interface{}) error {
+	if len(path) == 0 {
+		return ErrEmptyPath
+	}
+
+	for _, key := range path[:len(path)-1] {
+		v, ok := d.Get(key)
+		if !ok {
+			v = d.newLike()
+			d.Set(key, v)
+		}
+		next, ok := v.(*Dictionary)
+		if !ok {
+			return ErrNotDictionary
+		}
+		d = next
+	}
+
+	d.Set(path[len(path)-1], val)
+	return nil
+}
+
+// GetPath returns the value at a path of keys through nested dictionaries.
+// The second return value will be false if the path is not found.
+func (d *Dictionary) GetPath(path []Hasher) (interface{}, bool) {
+	var v interface{} = d
+	for _, key := range path {
+		next, ok := v.(*Dictionary)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = next.Get(key); !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// helper to split a dotted path into StringKeys.
+func dottedPath(path string) []Hasher {
+	parts := strings.Split(path, ".")
+	keys := make([]Hasher, len(parts))
+	for n, p := range parts {
+		keys[n] = StringKey(p)
+	}
+	return keys
+}
+
+// SetDotted is like SetPath, but takes a dotted string such as "server.port",
+// using a StringKey for each part.
+func (d *Dictionary) SetDotted(path string, val interface{}) error {
+	return d.SetPath(dottedPath(path), val)
+}
+
+// GetDotted is like GetPath, but takes a dotted string such as "server.port",
+// using a StringKey for each part.
+func (d *Dictionary) GetDotted(path string) (interface{}, bool) {
+	return d.GetPath(dottedPath(path))
+}
@@ -0,0 +1,312 @@
+package dictionary
+
+import (
+	"hash/maphash"
+	"math/bits"
+)
+
+// Persistent is an immutable dictionary. Set and Delete return a new
+// Persistent rather than changing the receiver, sharing most of its
+// structure with the old one, so each version can be kept and read without
+// locking while a writer produces new ones.
+//
+// Entries are kept in a hash array mapped trie: each level of the trie uses
+// 5 bits of the key's hash to choose a child, and only the nodes on the path
+// to a changed key are copied.
+type Persistent struct {
+	root  *persistentNode
+	count int
+
+	seed       maphash.Seed
+	stringHash StringHashFunc
+}
+
+type (
+	persistentNode struct {
+		// a bit is set for each child present, and the entries are in
+		// the order of their bits.
+		bitmap  uint32
+		entries []persistentEntry
+	}
+
+	// persistentEntry is either a key and value, or a node for the next
+	// level of the trie.
+	persistentEntry struct {
+		hash  uint64
+		key   Hasher
+		value interface{}
+		node  *persistentNode
+	}
+)
+
+const (
+	persistentBits = 5
+	persistentMask = 1<<persistentBits - 1
+)
+
+// NewPersistent creates an empty Persistent dictionary. Options are applied
+// as for New, but only those that change how keys are hashed, such as
+// SetHashSeed and WithStringHash, have any effect.
+func NewPersistent(options ...OptionsFunc) *Persistent {
+	d := &Dictionary{}
+	for _, f := range options {
+		f(d)
+	}
+	if d.seed == (maphash.Seed{}) {
+		d.seed = maphash.MakeSeed()
+	}
+	return &Persistent{
+		seed:       d.seed,
+		stringHash: d.stringHash,
+	}
+}
+
+// helper to return a new version with a different root.
+func (p *Persistent) with(root *persistentNode, count int) *Persistent {
+	return &Persistent{
+		root:       root,
+		count:      count,
+		seed:       p.seed,
+		stringHash: p.stringHash,
+	}
+}
+
+// Get returns an item from the dictionary. The second return value will be
+// false if not found.
+func (p *Persistent) Get(key Hasher) (interface{}, bool) {
+	if e := p.root.find(key, hashWith(key, p.seed, p.stringHash), 0); e != nil {
+		return e.value, true
+	}
+	return nil, false
+}
+
+// Contains reports whether key is present in the dictionary.
+func (p *Persistent) Contains(key Hasher) bool {
+	return p.root.find(key, hashWith(key, p.seed, p.stringHash), 0) != nil
+}
+
+// Set returns a new version of the dictionary with key set to val. The
+// receiver is not changed.
+func (p *Persistent) Set(key Hasher, val interface{}) *Persistent {
+	e := persistentEntry{
+		hash:  hashWith(key, p.seed, p.stringHash),
+		key:   key,
+		value: val,
+	}
+	root, added := p.root.set(e, 0)
+	count := p.count
+	if added {
+		count++
+	}
+	return p.with(root, count)
+}
+
+// Delete returns a new version of the dictionary without key. If key is not
+// present, the receiver is returned.
+func (p *Persistent) Delete(key Hasher) *Persistent {
+	root, removed := p.root.delete(key, hashWith(key, p.seed, p.stringHash), 0)
+	if !removed {
+		return p
+	}
+	return p.with(root, p.count-1)
+}
+
+// Len returns the number of entries in the dictionary.
+func (p *Persistent) Len() int {
+	return p.count
+}
+
+// Each executes the function on each element. Error returned will be any
+// error the EachFunc returned to stop iteration.
+func (p *Persistent) Each(f EachFunc) error {
+	return p.root.each(f)
+}
+
+// Keys returns all the keys in the dictionary.
+func (p *Persistent) Keys() []Hasher {
+	keys := make([]Hasher, 0, p.count)
+	p.Each(func(k Hasher, _ interface{}) error {
+		keys = append(keys, k)
+		return nil
+	})
+	return keys
+}
+
+// index returns the bit for h at the level given by shift, and the position
+// of its entry.
+func (n *persistentNode) index(h uint64, shift uint) (uint32, int) {
+	bit := uint32(1) << ((h >> shift) & persistentMask)
+	return bit, bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// once all of the bits of the hash are used, the keys in a node all have the
+// same hash, and the entries are just a list.
+func collisionLevel(shift uint) bool {
+	return shift >= 64
+}
+
+func (n *persistentNode) find(key Hasher, h uint64, shift uint) *persistentEntry {
+	for n != nil {
+		if collisionLevel(shift) {
+			for i := range n.entries {
+				if e := &n.entries[i]; key.Equal(e.key) {
+					return e
+				}
+			}
+			return nil
+		}
+		bit, i := n.index(h, shift)
+		if n.bitmap&bit == 0 {
+			return nil
+		}
+		e := &n.entries[i]
+		if e.node == nil {
+			if e.hash == h && key.Equal(e.key) {
+				return e
+			}
+			return nil
+		}
+		n = e.node
+		shift += persistentBits
+	}
+	return nil
+}
+
+// set returns a copy of the node with the entry added or replaced, and
+// whether it was added.
+func (n *persistentNode) set(e persistentEntry, shift uint) (*persistentNode, bool) {
+	if n == nil {
+		n = &persistentNode{}
+	}
+	if collisionLevel(shift) {
+		for i := range n.entries {
+			if e.key.Equal(n.entries[i].key) {
+				return n.replace(i, e), false
+			}
+		}
+		entries := make([]persistentEntry, len(n.entries), len(n.entries)+1)
+		copy(entries, n.entries)
+		return &persistentNode{entries: append(entries, e)}, true
+	}
+
+	bit, i := n.index(e.hash, shift)
+	if n.bitmap&bit == 0 {
+		entries := make([]persistentEntry, len(n.entries)+1)
+		copy(entries, n.entries[:i])
+		entries[i] = e
+		copy(entries[i+1:], n.entries[i:])
+		return &persistentNode{bitmap: n.bitmap | bit, entries: entries}, true
+	}
+
+	old := n.entries[i]
+	switch {
+	case old.node != nil:
+		child, added := old.node.set(e, shift+persistentBits)
+		return n.replace(i, persistentEntry{node: child}), added
+	case old.hash == e.hash && e.key.Equal(old.key):
+		return n.replace(i, e), false
+	}
+	child := mergeEntries(old, e, shift+persistentBits)
+	return n.replace(i, persistentEntry{node: child}), true
+}
+
+// replace returns a copy of the node with the i'th entry replaced.
+func (n *persistentNode) replace(i int, e persistentEntry) *persistentNode {
+	entries := make([]persistentEntry, len(n.entries))
+	copy(entries, n.entries)
+	entries[i] = e
+	return &persistentNode{bitmap: n.bitmap, entries: entries}
+}
+
+// mergeEntries returns a node holding two entries that share a slot at the
+// previous level.
+func mergeEntries(a, b persistentEntry, shift uint) *persistentNode {
+	if collisionLevel(shift) {
+		return &persistentNode{entries: []persistentEntry{a, b}}
+	}
+	ia := (a.hash >> shift) & persistentMask
+	ib := (b.hash >> shift) & persistentMask
+	switch {
+	case ia == ib:
+		return &persistentNode{
+			bitmap:  1 << ia,
+			entries: []persistentEntry{{node: mergeEntries(a, b, shift+persistentBits)}},
+		}
+	case ia > ib:
+		a, b = b, a
+	}
+	return &persistentNode{
+		bitmap:  1<<ia | 1<<ib,
+		entries: []persistentEntry{a, b},
+	}
+}
+
+// delete returns a copy of the node without key, or nil if the node is left
+// empty, and whether the key was found.
+func (n *persistentNode) delete(key Hasher, h uint64, shift uint) (*persistentNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+	if collisionLevel(shift) {
+		for i := range n.entries {
+			if key.Equal(n.entries[i].key) {
+				return n.remove(0, i), true
+			}
+		}
+		return n, false
+	}
+
+	bit, i := n.index(h, shift)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	e := n.entries[i]
+	if e.node == nil {
+		if e.hash != h || !key.Equal(e.key) {
+			return n, false
+		}
+		return n.remove(bit, i), true
+	}
+
+	child, removed := e.node.delete(key, h, shift+persistentBits)
+	switch {
+	case !removed:
+		return n, false
+	case child == nil:
+		return n.remove(bit, i), true
+	case len(child.entries) == 1 && child.entries[0].node == nil:
+		// a single key does not need a level of its own.
+		return n.replace(i, child.entries[0]), true
+	}
+	return n.replace(i, persistentEntry{node: child}), true
+}
+
+// remove returns a copy of the node without the i'th entry, whose bit is
+// given, or nil if it would be empty.
+func (n *persistentNode) remove(bit uint32, i int) *persistentNode {
+	if len(n.entries) == 1 {
+		return nil
+	}
+	entries := make([]persistentEntry, 0, len(n.entries)-1)
+	entries = append(entries, n.entries[:i]...)
+	entries = append(entries, n.entries[i+1:]...)
+	return &persistentNode{bitmap: n.bitmap &^ bit, entries: entries}
+}
+
+func (n *persistentNode) each(f EachFunc) error {
+	if n == nil {
+		return nil
+	}
+	for _, e := range n.entries {
+		var err error
+		if e.node != nil {
+			err = e.node.each(f)
+		} else {
+			err = f(e.key, e.value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package dictionary
+
+import "sync"
+
+// WithItemPool sets whether items are reused after they are removed from the
+// dictionary, rather than allocating a new one for each entry. This reduces
+// the work for the garbage collector when entries are frequently added and
+// removed. It is disabled by default.
+func WithItemPool(enabled bool) OptionsFunc {
+	return func(d *Dictionary) {
+		d.items = nil
+		if enabled {
+			d.items = &sync.Pool{
+				New: func() interface{} { return new(item) },
+			}
+		}
+	}
+}
+
+// helper to get an item, from the arena or pool if there is one.
+func (d *Dictionary) newItem(key Hasher, h uint64, val interface{}) *item {
+	var i *item
+	switch {
+	case d.arena != nil:
+		i = d.arena.alloc()
+	case d.items != nil:
+		i = d.items.Get().(*item)
+	default:
+		i = new(item)
+	}
+	i.key = key
+	i.hash = h
+	i.value = val
+	return i
+}
+
+// helper to return a removed item to the arena or pool, if there is one.
+func (d *Dictionary) freeItem(i *item) {
+	if d.arena != nil {
+		d.arena.release(i)
+		return
+	}
+	if d.items == nil {
+		return
+	}
+	// do not keep the key and value alive.
+	*i = item{}
+	d.items.Put(i)
+}
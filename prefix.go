@@ -0,0 +1,132 @@
+package dictionary
+
+import (
+	"sort"
+	"strings"
+)
+
+// prefixIndex keeps the items with StringKey keys sorted by key, so the keys
+// with a given prefix are next to each other. Adding and removing an item
+// moves the items after it, which is fast in practice for all but very large
+// dictionaries.
+type prefixIndex struct {
+	items []*item
+}
+
+// WithPrefixIndex keeps an index of StringKey keys, so KeysWithPrefix and
+// EachWithPrefix only look at the matching keys rather than every entry, and
+// return them in order. It makes adding and removing entries slower. Keys of
+// other types are not indexed.
+func WithPrefixIndex() OptionsFunc {
+	return func(d *Dictionary) {
+		d.prefixes = &prefixIndex{}
+	}
+}
+
+// prefixString returns the string of a StringKey, even if wrapped by
+// CachedKey.
+func prefixString(key Hasher) (string, bool) {
+	if c, ok := key.(*CachedHasher); ok {
+		key = c.Key
+	}
+	s, ok := key.(StringKey)
+	return string(s), ok
+}
+
+// search returns the position of the first item whose key is not before s.
+func (x *prefixIndex) search(s string) int {
+	return sort.Search(len(x.items), func(n int) bool {
+		k, _ := prefixString(x.items[n].key)
+		return k >= s
+	})
+}
+
+func (x *prefixIndex) insert(i *item) {
+	s, ok := prefixString(i.key)
+	if !ok {
+		return
+	}
+	n := x.search(s)
+	x.items = append(x.items, nil)
+	copy(x.items[n+1:], x.items[n:])
+	x.items[n] = i
+}
+
+func (x *prefixIndex) delete(i *item) {
+	s, ok := prefixString(i.key)
+	if !ok {
+		return
+	}
+	for n := x.search(s); n < len(x.items); n++ {
+		if x.items[n] == i {
+			x.items = deleteItem(x.items, n)
+			return
+		}
+	}
+}
+
+// matching returns the items whose keys start with prefix.
+func (x *prefixIndex) matching(prefix string) []*item {
+	start := x.search(prefix)
+	end := start
+	for end < len(x.items) {
+		if k, _ := prefixString(x.items[end].key); !strings.HasPrefix(k, prefix) {
+			break
+		}
+		end++
+	}
+	return x.items[start:end]
+}
+
+// KeysWithPrefix returns the StringKey keys that start with prefix. With
+// WithPrefixIndex, they are found using the index and returned in order.
+// Otherwise, every entry is checked.
+func (d *Dictionary) KeysWithPrefix(prefix string) []Hasher {
+	var keys []Hasher
+	// EachWithPrefix only returns the errors our function returns.
+	_ = d.EachWithPrefix(prefix, func(k Hasher, _ interface{}) error {
+		keys = append(keys, k)
+		return nil
+	})
+	return keys
+}
+
+// EachWithPrefix executes the function on each element with a StringKey key
+// that starts with prefix. With WithPrefixIndex, they are found using the index
+// and passed in order. Otherwise, every entry is checked. f may add, change, or
+// delete entries; entries it deletes are not passed to it afterwards. Error
+// returned will be any error the EachFunc returned to stop iteration.
+func (d *Dictionary) EachWithPrefix(prefix string, f EachFunc) error {
+	// the keys are copied, so f may change the dictionary. Each is looked up
+	// again before f is called, as f may have removed it, and its item may
+	// since have been reused for another key.
+	var keys []Hasher
+	var hashes []uint64
+	match := func(i *item) {
+		keys = append(keys, i.key)
+		hashes = append(hashes, i.hash)
+	}
+	if d.prefixes != nil {
+		for _, i := range d.prefixes.matching(prefix) {
+			match(i)
+		}
+	} else {
+		d.iterate(func(i *item) bool {
+			if s, ok := prefixString(i.key); ok && strings.HasPrefix(s, prefix) {
+				match(i)
+			}
+			return true
+		})
+	}
+
+	for n, key := range keys {
+		i := d.find(key, hashes[n])
+		if i == nil {
+			continue
+		}
+		if err := f(i.key, i.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
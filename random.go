@@ -0,0 +1,114 @@
+package dictionary
+
+import "math/rand"
+
+// randomIndex keeps every item in a slice, so one can be picked at random in
+// constant time. Each item records its position, so it can be removed by
+// moving the last item into its place.
+type randomIndex struct {
+	items []*item
+}
+
+// WithRandomAccess keeps an index of the entries, so RandomKey and PopRandom
+// take constant time rather than looking through the entries. It uses a little
+// more memory, and makes adding and removing entries slightly slower.
+func WithRandomAccess() OptionsFunc {
+	return func(d *Dictionary) {
+		d.random = &randomIndex{}
+	}
+}
+
+func (x *randomIndex) insert(i *item) {
+	i.pos = len(x.items)
+	x.items = append(x.items, i)
+}
+
+func (x *randomIndex) delete(i *item) {
+	last := len(x.items) - 1
+	moved := x.items[last]
+	x.items[i.pos] = moved
+	moved.pos = i.pos
+	// do not keep the item alive.
+	x.items[last] = nil
+	x.items = x.items[:last]
+}
+
+// helper to pick an item uniformly at random, or nil if the dictionary is
+// empty.
+func (d *Dictionary) randomItem() *item {
+	if d.random == nil {
+		// Len removes expired items, so the walk sees exactly this many.
+		n := d.Len()
+		if n == 0 {
+			return nil
+		}
+		var found *item
+		skip := rand.Intn(n)
+		d.walk(func(i *item) bool {
+			if skip == 0 {
+				found = i
+				return false
+			}
+			skip--
+			return true
+		})
+		return found
+	}
+
+	now := d.now()
+	for len(d.random.items) > 0 {
+		i := d.random.items[rand.Intn(len(d.random.items))]
+		if !i.expired(now) {
+			return i
+		}
+		d.expire(i)
+	}
+	return nil
+}
+
+// RandomKey returns a key chosen uniformly at random. The second return value
+// will be false if the dictionary is empty. Without WithRandomAccess, this
+// looks through half of the entries on average.
+func (d *Dictionary) RandomKey() (Hasher, bool) {
+	i := d.randomItem()
+	if i == nil {
+		return nil, false
+	}
+	return i.key, true
+}
+
+// PopRandom removes an entry chosen uniformly at random and returns it. The
+// last return value will be false if the dictionary is empty.
+func (d *Dictionary) PopRandom() (Hasher, interface{}, bool) {
+	i := d.randomItem()
+	if i == nil {
+		return nil, nil, false
+	}
+	key, val := i.key, i.value
+	d.delete(i)
+	return key, val, true
+}
+
+// Sample returns n entries chosen uniformly at random, in a single pass over
+// the entries using reservoir sampling. If the dictionary has n entries or
+// fewer, all of them are returned. The order of the entries is unspecified.
+func (d *Dictionary) Sample(n int) []Item {
+	if n <= 0 {
+		return nil
+	}
+	items := make([]Item, 0, n)
+	seen := 0
+	d.walk(func(i *item) bool {
+		seen++
+		if len(items) < n {
+			items = append(items, Item{Key: i.key, Value: i.value})
+			return true
+		}
+		// keep each entry seen so far with probability n/seen.
+		if r := rand.Intn(seen); r < n {
+			items[r] = Item{Key: i.key, Value: i.value}
+		}
+		return true
+	})
+	return items
+}
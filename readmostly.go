@@ -0,0 +1,87 @@
+package dictionary
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ReadMostly is a dictionary that is safe for concurrent use, for workloads
+// with far more reads than writes. Readers never lock: they load the current
+// version, a Persistent dictionary that never changes, and read from it.
+// Writers are serialized by a mutex, copy only the nodes on the path to the
+// changed key, and publish the new version atomically. Readers on other cores
+// never contend with each other, unlike with a sync.RWMutex.
+type ReadMostly struct {
+	mu      sync.Mutex
+	current atomic.Pointer[Persistent]
+}
+
+// NewReadMostly creates an empty ReadMostly dictionary. Options are passed to
+// NewPersistent.
+func NewReadMostly(options ...OptionsFunc) *ReadMostly {
+	r := &ReadMostly{}
+	r.current.Store(NewPersistent(options...))
+	return r
+}
+
+// Snapshot returns the current version. It does not change as later writes
+// are made, so it can be read or iterated without locking.
+func (r *ReadMostly) Snapshot() *Persistent {
+	return r.current.Load()
+}
+
+// Get returns an item from the dictionary. The second return value will be
+// false if not found.
+func (r *ReadMostly) Get(key Hasher) (interface{}, bool) {
+	return r.Snapshot().Get(key)
+}
+
+// Contains reports whether key is present in the dictionary.
+func (r *ReadMostly) Contains(key Hasher) bool {
+	return r.Snapshot().Contains(key)
+}
+
+// Len returns the number of entries in the dictionary.
+func (r *ReadMostly) Len() int {
+	return r.Snapshot().Len()
+}
+
+// Each executes the function on each element of the current version. Writes
+// made while it runs are not seen. Error returned will be any error the
+// EachFunc returned to stop iteration.
+func (r *ReadMostly) Each(f EachFunc) error {
+	return r.Snapshot().Each(f)
+}
+
+// Keys returns all the keys in the current version.
+func (r *ReadMostly) Keys() []Hasher {
+	return r.Snapshot().Keys()
+}
+
+// Set sets the value for key.
+func (r *ReadMostly) Set(key Hasher, val interface{}) {
+	r.Update(func(p *Persistent) *Persistent {
+		return p.Set(key, val)
+	})
+}
+
+// Delete removes key from the dictionary. It returns false if the key was not
+// present.
+func (r *ReadMostly) Delete(key Hasher) bool {
+	var found bool
+	r.Update(func(p *Persistent) *Persistent {
+		next := p.Delete(key)
+		found = next != p
+		return next
+	})
+	return found
+}
+
+// Update replaces the current version with the one returned by f, which is
+// passed the current version. Writers are serialized, so f sees every earlier
+// write, and several changes made by f are published at once.
+func (r *ReadMostly) Update(f func(p *Persistent) *Persistent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current.Store(f(r.current.Load()))
+}
@@ -0,0 +1,48 @@
+package dictionary
+
+// View is a read-only view of a Dictionary, for handing a dictionary to code
+// that should not modify it. It has no methods that change the entries, and
+// it is not a copy: changes made through the Dictionary are seen by the view.
+type View struct {
+	d *Dictionary
+}
+
+// ReadOnly returns a read-only view of the dictionary.
+func (d *Dictionary) ReadOnly() View {
+	return View{d: d}
+}
+
+// Get returns an item from the dictionary. The second return value will be
+// false if not found. Unlike Dictionary.Get, missing entries are never created
+// by a default factory.
+func (v View) Get(key Hasher) (interface{}, bool) {
+	key, _, i := v.d.lookup(key)
+	if i == nil {
+		v.d.miss(key)
+		return nil, false
+	}
+	v.d.hit(key)
+	v.d.touch(i)
+	return i.value, true
+}
+
+// Contains reports whether key is present in the dictionary.
+func (v View) Contains(key Hasher) bool {
+	return v.d.Contains(key)
+}
+
+// Len returns the number of entries in the dictionary.
+func (v View) Len() int {
+	return v.d.Len()
+}
+
+// Each executes the function on each element. Error returned will be any
+// error the EachFunc returned to stop iteration.
+func (v View) Each(f EachFunc) error {
+	return v.d.Each(f)
+}
+
+// Keys returns all the keys in the dictionary.
+func (v View) Keys() []Hasher {
+	return v.d.Keys()
+}
@@ -0,0 +1,138 @@
+package dictionary
+
+// WithCapacity sizes the dictionary to hold n entries without growing, or,
+// for the Chaining backend, with about one entry per bucket. If the number of
+// buckets is also set, the larger of the two is used.
+func WithCapacity(n int) OptionsFunc {
+	return func(d *Dictionary) {
+		d.capacity = n
+	}
+}
+
+// Reserve resizes the dictionary, if needed, so it can hold n entries without
+// growing. This avoids resizing repeatedly when adding many entries.
+func (d *Dictionary) Reserve(n int) {
+	if n > d.store.capacity() {
+		d.rehash(d.bucketsFor(n))
+	}
+}
+
+// helper to return the number of buckets needed to hold n entries with the
+// dictionary's backend. It returns zero if n is not positive.
+func (d *Dictionary) bucketsFor(n int) uint32 {
+	if n <= 0 {
+		return 0
+	}
+	switch d.backend {
+	case OpenAddressing:
+		// these tables are sized in powers of two, so this is rounded up.
+		return uint32(n*4/3 + 1)
+	case SwissTable:
+		return uint32(n*8/7 + 1)
+	case Cuckoo, GoMap:
+		return uint32(n)
+	}
+	// a prime number of buckets spreads keys with a poor hash best.
+	return nextPrime(uint32(n))
+}
+
+// nextPrime returns the smallest prime that is at least n.
+func nextPrime(n uint32) uint32 {
+	if n <= 2 {
+		return 2
+	}
+	if n%2 == 0 {
+		n++
+	}
+	for ; ; n += 2 {
+		prime := true
+		for f := uint32(3); f*f <= n; f += 2 {
+			if n%f == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			return n
+		}
+	}
+}
+
+// Shrink resizes the dictionary to fit the entries it holds, returning the
+// memory used by a larger table. It never shrinks below the default number of
+// buckets. See WithAutoShrink to do this as entries are removed.
+func (d *Dictionary) Shrink() {
+	d.removeExpired()
+	d.shrink()
+}
+
+// helper to shrink the dictionary without removing expired entries first, as
+// this is called while they are being removed.
+func (d *Dictionary) shrink() {
+	n := d.bucketsFor(d.count)
+	if n < defaultBuckets {
+		n = defaultBuckets
+	}
+	s := d.newStore(n)
+	if s.capacity() >= d.store.capacity() {
+		return
+	}
+	d.numBuckets = n
+	d.moveTo(s)
+}
+
+// WithAutoShrink shrinks the dictionary whenever the number of entries falls
+// below fraction of its capacity, such as 0.25. Small dictionaries are not
+// shrunk. It is disabled by default.
+func WithAutoShrink(fraction float64) OptionsFunc {
+	return func(d *Dictionary) {
+		d.shrinkFraction = fraction
+	}
+}
+
+// helper to shrink the dictionary if it has auto shrinking enabled and enough
+// entries have been removed.
+func (d *Dictionary) autoShrink() {
+	if d.shrinkFraction <= 0 {
+		return
+	}
+	capacity := d.store.capacity()
+	// tables this small are not worth shrinking.
+	if capacity <= 2*defaultBuckets {
+		return
+	}
+	if float64(d.count) < d.shrinkFraction*float64(capacity) {
+		d.shrink()
+	}
+}
+
+// Rehash moves the entries into n buckets, such as after loading many entries
+// into a dictionary created with the default number. Backends that grow as
+// needed use n as the size to start from, though it is never too small to
+// hold the entries. If n is zero, the default is used.
+func (d *Dictionary) Rehash(n uint32) {
+	if n == 0 {
+		n = defaultBuckets
+	}
+	d.rehash(n)
+}
+
+// helper to move the items into a new store with n buckets.
+func (d *Dictionary) rehash(n uint32) {
+	d.numBuckets = n
+	d.moveTo(d.newStore(n))
+}
+
+// helper to move the items into s. The stored hashes are reused, so no keys
+// are hashed again. The old store is left as is, so a cursor over it is still
+// valid.
+func (d *Dictionary) moveTo(s store) {
+	c := d.store.cursor()
+	for i := c.next(); i != nil; i = c.next() {
+		s.insert(i)
+	}
+	d.store = s
+	if d.observer != nil {
+		d.observer.Rehash(s.capacity())
+	}
+}
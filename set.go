@@ -0,0 +1,216 @@
+package dictionary
+
+import "hash/maphash"
+
+// Set is a collection of keys without values. It hashes keys the same way as
+// a Dictionary, but stores only each key and its hash, in buckets that grow as
+// keys are added, so no memory is spent on values or on the bookkeeping of
+// dictionary entries.
+type Set struct {
+	seed       maphash.Seed
+	stringHash StringHashFunc
+	normalizer NormalizeFunc
+	buckets    [][]setEntry
+	count      int
+}
+
+type setEntry struct {
+	key  Hasher
+	hash uint64
+}
+
+// NewSet creates an empty set. Of the options, only those for hashing and
+// sizing, such as SetHashSeed, WithStringHash, WithKeyNormalizer and
+// SetBuckets, are used.
+func NewSet(options ...OptionsFunc) *Set {
+	d := newDictionary(options)
+	if d.seed == (maphash.Seed{}) {
+		d.seed = maphash.MakeSeed()
+	}
+	n := d.numBuckets
+	if b := d.bucketsFor(d.capacity); b > n {
+		n = b
+	}
+	return &Set{
+		seed:       d.seed,
+		stringHash: d.stringHash,
+		normalizer: d.normalizer,
+		buckets:    make([][]setEntry, max(n, 1)),
+	}
+}
+
+// helper to create an empty set that hashes keys the same way as s.
+func (s *Set) newLike() *Set {
+	return &Set{
+		seed:       s.seed,
+		stringHash: s.stringHash,
+		normalizer: s.normalizer,
+		buckets:    make([][]setEntry, defaultBuckets),
+	}
+}
+
+// helper to normalize and hash a key.
+func (s *Set) hash(key Hasher) (Hasher, uint64) {
+	if key == nil {
+		panic(ErrNilKey)
+	}
+	if s.normalizer != nil {
+		key = s.normalizer(key)
+	}
+	return key, hashWith(key, s.seed, s.stringHash)
+}
+
+// helper to return the bucket for a hash and the position of key in it, or -1
+// if it is not present.
+func (s *Set) find(key Hasher, h uint64) ([]setEntry, int) {
+	b := s.buckets[h%uint64(len(s.buckets))]
+	for n, e := range b {
+		if e.hash == h && e.key.Equal(key) {
+			return b, n
+		}
+	}
+	return b, -1
+}
+
+// helper to add a key that is known not to be present.
+func (s *Set) insert(e setEntry) {
+	if s.count >= len(s.buckets) {
+		s.grow()
+	}
+	n := e.hash % uint64(len(s.buckets))
+	s.buckets[n] = append(s.buckets[n], e)
+	s.count++
+}
+
+// helper to double the number of buckets. The stored hashes are reused.
+func (s *Set) grow() {
+	old := s.buckets
+	s.buckets = make([][]setEntry, 2*len(old)+1)
+	for _, b := range old {
+		for _, e := range b {
+			n := e.hash % uint64(len(s.buckets))
+			s.buckets[n] = append(s.buckets[n], e)
+		}
+	}
+}
+
+// Add adds key to the set. It returns true if the key was not already present.
+func (s *Set) Add(key Hasher) bool {
+	key, h := s.hash(key)
+	if _, n := s.find(key, h); n >= 0 {
+		return false
+	}
+	s.insert(setEntry{key: key, hash: h})
+	return true
+}
+
+// Remove removes key from the set. It returns true if the key was present.
+func (s *Set) Remove(key Hasher) bool {
+	key, h := s.hash(key)
+	b, n := s.find(key, h)
+	if n < 0 {
+		return false
+	}
+	// move the last key in the bucket into the hole.
+	last := len(b) - 1
+	b[n] = b[last]
+	b[last] = setEntry{}
+	s.buckets[h%uint64(len(s.buckets))] = b[:last]
+	s.count--
+	return true
+}
+
+// Contains reports whether key is in the set.
+func (s *Set) Contains(key Hasher) bool {
+	key, h := s.hash(key)
+	_, n := s.find(key, h)
+	return n >= 0
+}
+
+// Len returns the number of keys in the set.
+func (s *Set) Len() int {
+	return s.count
+}
+
+// Keys returns all of the keys in the set.
+func (s *Set) Keys() []Hasher {
+	keys := make([]Hasher, 0, s.count)
+	for _, b := range s.buckets {
+		for _, e := range b {
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
+// Each executes the function on each key. Error returned will be any error
+// the function returned to stop iteration. f may remove the key it is passed,
+// but must not otherwise add or remove keys.
+func (s *Set) Each(f func(Hasher) error) error {
+	for n := range s.buckets {
+		// backwards, as removing a key moves the last one in its bucket,
+		// which has already been visited, into its place.
+		for i := len(s.buckets[n]) - 1; i >= 0; i-- {
+			if i >= len(s.buckets[n]) {
+				continue
+			}
+			if err := f(s.buckets[n][i].key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// helper to call f on each entry.
+func (s *Set) each(f func(e setEntry)) {
+	for _, b := range s.buckets {
+		for _, e := range b {
+			f(e)
+		}
+	}
+}
+
+// helper to report whether keys and hashes stored in from can be used in s,
+// as for dictionaries.
+func (s *Set) sameHashing(from *Set) bool {
+	return s == from || (s.seed == from.seed && s.stringHash == nil && from.stringHash == nil && s.normalizer == nil)
+}
+
+// helper to find an entry from another set. The stored hash is reused if both
+// sets hash keys the same way.
+func (s *Set) contains(from *Set, e setEntry) bool {
+	if !s.sameHashing(from) {
+		return s.Contains(e.key)
+	}
+	_, n := s.find(e.key, e.hash)
+	return n >= 0
+}
+
+// Union returns a new set containing the keys in either set. It hashes keys
+// the same way as s.
+func (s *Set) Union(other *Set) *Set {
+	u := s.newLike()
+	s.each(u.insert)
+	other.each(func(e setEntry) {
+		if !u.contains(other, e) {
+			if !u.sameHashing(other) {
+				e.key, e.hash = u.hash(e.key)
+			}
+			u.insert(e)
+		}
+	})
+	return u
+}
+
+// Intersect returns a new set containing the keys in both sets. It hashes keys
+// the same way as s.
+func (s *Set) Intersect(other *Set) *Set {
+	i := s.newLike()
+	s.each(func(e setEntry) {
+		if other.contains(s, e) {
+			i.insert(e)
+		}
+	})
+	return i
+}
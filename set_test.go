@@ -0,0 +1,61 @@
+package dictionary_test
+
+import (
+	"testing"
+
+	"github.com/bakins/dictionary"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCollection(t *testing.T) {
+	a := dictionary.NewSet()
+	b := dictionary.NewSet()
+
+	for _, k := range []string{"a", "b", "c"} {
+		require.Equal(t, true, a.Add(dictionary.StringKey(k)), "should have added key")
+	}
+	require.Equal(t, false, a.Add(dictionary.StringKey("a")), "should not have added key")
+
+	for _, k := range []string{"b", "c", "d"} {
+		b.Add(dictionary.StringKey(k))
+	}
+
+	require.Equal(t, 4, a.Union(b).Len(), "unexpected length")
+
+	i := a.Intersect(b)
+	require.Equal(t, 2, i.Len(), "unexpected length")
+	require.Equal(t, true, i.Contains(dictionary.StringKey("b")), "should have found key")
+	require.Equal(t, false, i.Contains(dictionary.StringKey("a")), "should not have found key")
+
+	require.Equal(t, true, a.Remove(dictionary.StringKey("a")), "should have removed key")
+	require.Equal(t, false, a.Remove(dictionary.StringKey("a")), "should not have removed key")
+
+	n := 0
+	err := a.Each(func(dictionary.Hasher) error {
+		n++
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 2, n, "unexpected number of keys")
+	require.Equal(t, 2, len(a.Keys()), "unexpected number of keys")
+
+	// the buckets grow as keys are added, and f may remove the key it is
+	// passed.
+	c := dictionary.NewSet()
+	for n := 0; n < 1000; n++ {
+		require.Equal(t, true, c.Add(dictionary.Int64Key(n)), "should have added key")
+	}
+	require.Equal(t, 1000, c.Len(), "unexpected length")
+	require.Equal(t, 0, c.Intersect(dictionary.NewSet()).Len(), "unexpected length")
+	require.Equal(t, 1000+a.Len(), c.Union(a).Len(), "unexpected length")
+	err = c.Each(func(k dictionary.Hasher) error {
+		if k.(dictionary.Int64Key)%2 == 0 {
+			c.Remove(k)
+		}
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 500, c.Len(), "unexpected length")
+	require.Equal(t, false, c.Contains(dictionary.Int64Key(2)), "should not have found key")
+	require.Equal(t, true, c.Contains(dictionary.Int64Key(3)), "should have found key")
+}
@@ -0,0 +1,11 @@
+package dictionary
+
+// Snapshot returns a point-in-time copy of the dictionary, which can be
+// iterated while the original continues to receive writes. Like other methods,
+// it does not lock the dictionary, so a caller sharing it with goroutines that
+// write to it holds the lock while taking the snapshot, and may then release
+// it while using the copy. The copy has the same hashing and storage settings,
+// but no size limits, hooks, or other options.
+func (d *Dictionary) Snapshot() *Dictionary {
+	return d.filter(func(*item) bool { return true })
+}
@@ -0,0 +1,156 @@
+package dictionary
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Ordered may be implemented by keys that have a natural order. It is used
+// when no LessFunc is given, and by Min and Max.
+type Ordered interface {
+	Hasher
+	// Less must return true if the receiver sorts before the argument.
+	Less(Hasher) bool
+}
+
+// OrderedLess is a LessFunc for keys that implement Ordered. It panics if
+// a does not implement Ordered.
+func OrderedLess(a, b Hasher) bool {
+	return a.(Ordered).Less(b)
+}
+
+type itemSorter struct {
+	items []Item
+	less  LessFunc
+}
+
+func (s *itemSorter) Len() int           { return len(s.items) }
+func (s *itemSorter) Swap(i, j int)      { s.items[i], s.items[j] = s.items[j], s.items[i] }
+func (s *itemSorter) Less(i, j int) bool { return s.less(s.items[i].Key, s.items[j].Key) }
+
+// helper to collect the entries sorted by key.
+func (d *Dictionary) sortedItems(less LessFunc) []Item {
+	items := make([]Item, 0, d.Len())
+	d.walk(func(i *item) bool {
+		items = append(items, Item{Key: i.key, Value: i.value})
+		return true
+	})
+	if less == nil {
+		less = OrderedLess
+	}
+	sort.Sort(&itemSorter{items: items, less: less})
+	return items
+}
+
+// EachSorted executes the function on each element in the order given by
+// less. If less is nil, the keys must implement Ordered. Error returned will
+// be any error the EachFunc returned to stop iteration.
+func (d *Dictionary) EachSorted(less LessFunc, f EachFunc) error {
+	for _, i := range d.sortedItems(less) {
+		if err := f(i.Key, i.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SortedKeys returns all the keys in the order given by less. If less is nil,
+// the keys must implement Ordered.
+func (d *Dictionary) SortedKeys(less LessFunc) []Hasher {
+	items := d.sortedItems(less)
+	keys := make([]Hasher, len(items))
+	for n, i := range items {
+		keys[n] = i.Key
+	}
+	return keys
+}
+
+// extremeItem is the item with the smallest or largest key, once Min or Max
+// has scanned for it. It is kept up to date as items are added, and forgotten
+// if the item is removed, so only removing it leads to another scan.
+type extremeItem struct {
+	item  *item
+	valid bool
+}
+
+// the index into Dictionary.extremes.
+func extremeIndex(max bool) int {
+	if max {
+		return 1
+	}
+	return 0
+}
+
+// helper to report whether a sorts before b, or after b if max is set.
+func beyond(a, b *item, max bool) bool {
+	if max {
+		return b.key.(Ordered).Less(a.key)
+	}
+	return a.key.(Ordered).Less(b.key)
+}
+
+// helper to find the smallest entry, or the largest if max is set.
+func (d *Dictionary) extreme(max bool) (Hasher, interface{}, bool) {
+	e := &d.extremes[extremeIndex(max)]
+	if e.valid && e.item != nil && e.item.expired(d.now()) {
+		d.expire(e.item)
+	}
+	if !e.valid {
+		var found *item
+		d.walk(func(i *item) bool {
+			if found == nil || beyond(i, found, max) {
+				found = i
+			}
+			return true
+		})
+		*e = extremeItem{item: found, valid: true}
+	}
+	if e.item == nil {
+		return nil, nil, false
+	}
+	return e.item.key, e.item.value, true
+}
+
+// helper to update the smallest and largest items, if they are known, for an
+// added item. A key that cannot be compared with the remembered one, because
+// it is not Ordered or is of another type, is not compared at all; the item
+// is forgotten instead, so the next Min or Max scans the dictionary.
+func (d *Dictionary) addExtreme(i *item) {
+	for n := range d.extremes {
+		e := &d.extremes[n]
+		if !e.valid {
+			continue
+		}
+		if _, ok := i.key.(Ordered); !ok || (e.item != nil && reflect.TypeOf(i.key) != reflect.TypeOf(e.item.key)) {
+			d.extremes[n] = extremeItem{}
+			continue
+		}
+		if e.item == nil || beyond(i, e.item, n == 1) {
+			e.item = i
+		}
+	}
+}
+
+// helper to forget the smallest or largest item if it is removed.
+func (d *Dictionary) removeExtreme(i *item) {
+	for n := range d.extremes {
+		if d.extremes[n].item == i {
+			d.extremes[n] = extremeItem{}
+		}
+	}
+}
+
+// Min returns the entry with the smallest key. The keys must implement
+// Ordered. The last return value will be false if the dictionary is empty.
+// The smallest entry is remembered, so later calls only scan the dictionary
+// again if it has been removed.
+func (d *Dictionary) Min() (Hasher, interface{}, bool) {
+	return d.extreme(false)
+}
+
+// Max returns the entry with the largest key. The keys must implement
+// Ordered. The last return value will be false if the dictionary is empty.
+// Like Min, the largest entry is remembered.
+func (d *Dictionary) Max() (Hasher, interface{}, bool) {
+	return d.extreme(true)
+}
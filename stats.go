@@ -0,0 +1,71 @@
+package dictionary
+
+// Stats describes how the entries of a dictionary are spread across its
+// buckets. It can be used to evaluate a Hash implementation, or to choose the
+// number of buckets.
+//
+// For backends other than Chaining, each slot of the table is a bucket, and a
+// chain is the sequence of slots probed to find an entry.
+type Stats struct {
+	// Entries is the number of entries.
+	Entries int
+	// Buckets is the number of buckets.
+	Buckets int
+	// LoadFactor is the number of entries per bucket.
+	LoadFactor float64
+	// EmptyBuckets is the number of buckets with no entries.
+	EmptyBuckets int
+	// MaxChain is the length of the longest chain.
+	MaxChain int
+	// MeanChain is the average length of the chains that are not empty. For
+	// backends other than Chaining, it is the average number of slots probed
+	// to find an entry.
+	MeanChain float64
+	// Histogram counts the chains of each length, so Histogram[2] is the
+	// number of buckets with 2 entries. For backends other than Chaining, it
+	// counts the entries found after each number of probes.
+	Histogram []int
+	// Hits is the number of calls to Get and Contains that found the key.
+	Hits uint64
+	// Misses is the number of calls to Get and Contains that did not find
+	// the key.
+	Misses uint64
+}
+
+// Stats returns statistics about how the entries are spread across the
+// buckets.
+func (d *Dictionary) Stats() Stats {
+	s := Stats{
+		Entries: d.Len(),
+		Hits:    d.hits,
+		Misses:  d.misses,
+	}
+	// a zero Dictionary has no store yet.
+	if d.store != nil {
+		d.store.stats(&s)
+	}
+
+	if s.Buckets > 0 {
+		s.LoadFactor = float64(s.Entries) / float64(s.Buckets)
+	}
+	var chains, total int
+	for n, count := range s.Histogram {
+		if n > 0 && count > 0 {
+			s.MaxChain = n
+			chains += count
+			total += n * count
+		}
+	}
+	if chains > 0 {
+		s.MeanChain = float64(total) / float64(chains)
+	}
+	return s
+}
+
+// helper to count a chain of length n in the histogram.
+func (s *Stats) record(n int) {
+	for len(s.Histogram) <= n {
+		s.Histogram = append(s.Histogram, 0)
+	}
+	s.Histogram[n]++
+}
@@ -0,0 +1,88 @@
+package dictionary
+
+type (
+	// store is how a dictionary keeps its items. The dictionary handles
+	// hashing, expiration and bookkeeping, while the store only has to find,
+	// add and remove items by hash.
+	store interface {
+		// find returns the item for key, or nil if it is not present.
+		find(key Hasher, h uint64) *item
+		// insert adds an item that is known not to be present.
+		insert(i *item)
+		// delete removes the item, comparing by pointer. It returns false if
+		// the item was not found.
+		delete(i *item) bool
+		// cursor returns a cursor positioned before the first item.
+		cursor() cursor
+		// capacity returns how many items the store can hold before it
+		// grows, or before lookups slow down if it cannot grow.
+		capacity() int
+		// stats adds the number of buckets, empty buckets, and chain
+		// lengths to s.
+		stats(s *Stats)
+		// chain returns the bucket an item hashes to and the length of the
+		// chain searched to find it, measured as stats does.
+		chain(i *item) (bucket, length int)
+		// layout calls f with the items of each bucket, in order.
+		layout(f func(items []*item))
+	}
+
+	// cursor is used to iterate over a store. Deleting the item last
+	// returned must not affect the rest of the iteration.
+	cursor interface {
+		// next returns the next item, or nil when there are no more.
+		next() *item
+	}
+
+	// Backend selects how a dictionary stores its entries.
+	Backend int
+)
+
+const (
+	// Chaining stores entries in a fixed number of buckets, each a slice of
+	// the entries that hash to it. A bucket that collects more than a few
+	// entries is turned into an AVL tree, and back into a slice once it
+	// shrinks again. This is the default.
+	Chaining Backend = iota
+	// OpenAddressing stores entries in a single flat table, probing for a
+	// free slot on collisions. The table grows as entries are added, so the
+	// number of buckets is only used as the initial size.
+	OpenAddressing
+	// SwissTable stores entries in groups of 8 slots, with a byte of the hash
+	// of each entry kept alongside, so most slots can be ruled out without
+	// comparing keys. Like OpenAddressing, it grows as entries are added.
+	SwissTable
+	// Cuckoo stores entries using cuckoo hashing, so a lookup probes at most
+	// two slots. Inserts may move other entries, and grow the table if that
+	// takes too long.
+	Cuckoo
+	// GoMap stores entries in a built in Go map, keyed by the MapKey of
+	// keys that implement MapKeyer, or the keys themselves. It is a baseline
+	// to compare the other backends with, and an easy way to move code from
+	// a map to a dictionary. Stats and Dump show each entry as a bucket of
+	// its own, as the map's buckets cannot be seen.
+	GoMap
+)
+
+// WithBackend sets how the dictionary stores its entries.
+func WithBackend(b Backend) OptionsFunc {
+	return func(d *Dictionary) {
+		d.backend = b
+	}
+}
+
+// helper to create an empty store with n buckets for the dictionary's
+// backend.
+func (d *Dictionary) newStore(n uint32) store {
+	switch d.backend {
+	case OpenAddressing:
+		return newOpenTable(n)
+	case SwissTable:
+		return newSwissTable(n)
+	case Cuckoo:
+		return newCuckooTable(n)
+	case GoMap:
+		return newMapTable(n)
+	}
+	return newChainTable(n, d.bucketFactory)
+}
@@ -0,0 +1,119 @@
+package dictionary
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// streams start with a magic string and a version byte, so the format can
+// change in the future. This is followed by a gob stream of a streamHeader and
+// then each entry as an Item.
+const (
+	streamMagic   = "DICT"
+	streamVersion = 1
+)
+
+// ErrInvalidStream is returned by Decode when the data is not an encoded
+// dictionary.
+var ErrInvalidStream = errors.New("dictionary: invalid stream")
+
+type streamHeader struct {
+	Buckets uint32
+	Count   int
+	// set if the keys were encoded with a KeyCodec, in which case each
+	// entry is a codedItem.
+	KeyCodec bool
+}
+
+// Encode writes the dictionary to w one entry at a time, so the whole
+// encoding is never held in memory. Pointers to the live entries are collected
+// first, though, so the count written ahead of them is exact. As for
+// MarshalBinary, key and value types not registered by this package must be
+// registered with gob.Register, unless WithKeyCodec is set. w is written to
+// often, so callers may want to wrap it in a bufio.Writer.
+func (d *Dictionary) Encode(w io.Writer) error {
+	if _, err := io.WriteString(w, streamMagic+string([]byte{streamVersion})); err != nil {
+		return err
+	}
+
+	// the live entries are collected before the header is written, so its
+	// count matches the entries that follow even if some expire meanwhile.
+	items := make([]*item, 0, d.count)
+	d.walk(func(i *item) bool {
+		items = append(items, i)
+		return true
+	})
+
+	enc := gob.NewEncoder(w)
+	h := streamHeader{Buckets: d.numBuckets, Count: len(items), KeyCodec: d.keyCodec != nil}
+	if err := enc.Encode(h); err != nil {
+		return err
+	}
+
+	for _, i := range items {
+		if d.keyCodec == nil {
+			if err := enc.Encode(&Item{Key: i.key, Value: i.value}); err != nil {
+				return err
+			}
+			continue
+		}
+		c, err := d.codeItem(i)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(&c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode reads a dictionary written by Encode. The dictionary is created with
+// the number of buckets it was encoded with. Options are applied afterwards
+// and passed to New.
+func Decode(r io.Reader, options ...OptionsFunc) (*Dictionary, error) {
+	prefix := make([]byte, len(streamMagic)+1)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrInvalidStream
+		}
+		return nil, err
+	}
+	if string(prefix[:len(streamMagic)]) != streamMagic {
+		return nil, ErrInvalidStream
+	}
+	if v := prefix[len(streamMagic)]; v != streamVersion {
+		return nil, fmt.Errorf("dictionary: unsupported stream version %d", v)
+	}
+
+	dec := gob.NewDecoder(r)
+	var h streamHeader
+	if err := dec.Decode(&h); err != nil {
+		return nil, err
+	}
+
+	d := New(append([]OptionsFunc{SetBuckets(h.Buckets)}, options...)...)
+	if h.KeyCodec && d.keyCodec == nil {
+		return nil, ErrNoKeyCodec
+	}
+	for n := 0; n < h.Count; n++ {
+		if h.KeyCodec {
+			var c codedItem
+			if err := dec.Decode(&c); err != nil {
+				return nil, err
+			}
+			if err := d.setCoded(c); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var i Item
+		if err := dec.Decode(&i); err != nil {
+			return nil, err
+		}
+		d.Set(i.Key, i.Value)
+	}
+	return d, nil
+}
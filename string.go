@@ -1,15 +1,56 @@
 package dictionary
 
-import "hash/crc32"
+import (
+	"hash/crc32"
+	"hash/maphash"
+)
 
 // StringKey is a convinience type for using strings as keys in a dictionary
 type StringKey string
 
+// StringHashFunc is a hash function for strings.
+type StringHashFunc func(string) uint64
+
+// WithStringHash sets the hash function used for StringKey keys, instead of
+// the default seeded hash. FNV1a and CRC32 are provided.
+func WithStringHash(f StringHashFunc) OptionsFunc {
+	return func(d *Dictionary) {
+		d.stringHash = f
+	}
+}
+
+// FNV1a is a StringHashFunc using the 64 bit FNV-1a hash. It is fast and has
+// a good distribution, but is not seeded.
+func FNV1a(s string) uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime
+	}
+	return h
+}
+
+// CRC32 is a StringHashFunc using crc32, the same as StringKey.Hash.
+func CRC32(s string) uint64 {
+	return uint64(crc32.ChecksumIEEE([]byte(s)))
+}
+
 // Hash generates a hash for the string using crc32
 func (s StringKey) Hash() uint32 {
 	return crc32.ChecksumIEEE([]byte(string(s)))
 }
 
+// HashSeed generates a hash for the string using hash/maphash. The
+// dictionary uses this rather than Hash, so the layout of the buckets cannot
+// be predicted from the keys.
+func (s StringKey) HashSeed(seed maphash.Seed) uint64 {
+	return maphash.String(seed, string(s))
+}
+
 // Compare uses the stdlib strings.Compare to compare two string keys
 func (s StringKey) Equal(v interface{}) bool {
 	return string(s) == string(v.(StringKey))
@@ -19,3 +60,8 @@ func (s StringKey) Equal(v interface{}) bool {
 func (s StringKey) String() string {
 	return string(s)
 }
+
+// Less reports whether s sorts before v, which must also be a StringKey.
+func (s StringKey) Less(v Hasher) bool {
+	return string(s) < string(v.(StringKey))
+}
@@ -0,0 +1,281 @@
+package dictionary
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// swissTable is a store modeled on the "swiss table" design. Slots are
+// arranged in groups of 8, and each group has a control byte per slot: empty,
+// deleted, or 7 bits of the item's hash. A lookup scans the 8 control bytes of
+// a group at once, treated as a single uint64, and only compares keys for the
+// slots whose bits match. Groups are probed quadratically, and a lookup stops
+// at the first group with an empty slot.
+type swissTable struct {
+	groups []swissGroup
+	// full and deleted slots, used to decide when to grow.
+	used  int
+	count int
+}
+
+type swissGroup struct {
+	ctrl  [swissGroupSize]byte
+	items [swissGroupSize]*item
+}
+
+const (
+	swissGroupSize = 8
+
+	swissEmpty   byte = 0x80
+	swissDeleted byte = 0xfe
+
+	// the low and high bit of each byte of a control word.
+	swissLSB uint64 = 0x0101010101010101
+	swissMSB uint64 = 0x8080808080808080
+)
+
+func newSwissTable(n uint32) *swissTable {
+	size := 1
+	for size*swissGroupSize < int(n) {
+		size <<= 1
+	}
+	t := &swissTable{}
+	t.groups = newSwissGroups(size)
+	return t
+}
+
+func newSwissGroups(n int) []swissGroup {
+	groups := make([]swissGroup, n)
+	for g := range groups {
+		for s := range groups[g].ctrl {
+			groups[g].ctrl[s] = swissEmpty
+		}
+	}
+	return groups
+}
+
+// split the hash into the part used to choose the first group, and the 7 bits
+// kept in the control byte.
+func swissHash(h uint64) (uint64, byte) {
+	// keys with a poor Hash, such as sequential integers, would otherwise
+	// differ only in the low bits.
+	m := mix(h)
+	return m >> 7, byte(m & 0x7f)
+}
+
+// word returns the control bytes of a group, one per byte.
+func (g *swissGroup) word() uint64 {
+	return binary.LittleEndian.Uint64(g.ctrl[:])
+}
+
+// match returns a bitmask with the high bit set in each byte equal to b. It
+// may report false positives, so keys must still be compared.
+func swissMatch(w uint64, b byte) uint64 {
+	x := w ^ (swissLSB * uint64(b))
+	return (x - swissLSB) &^ x & swissMSB
+}
+
+// matchEmpty returns a bitmask of the empty slots in a control word.
+func swissMatchEmpty(w uint64) uint64 {
+	// only empty has the high bit set and the second lowest bit clear.
+	return w &^ (w << 6) & swissMSB
+}
+
+// matchFree returns a bitmask of the empty or deleted slots in a control
+// word.
+func swissMatchFree(w uint64) uint64 {
+	return w & swissMSB
+}
+
+// slot returns the index of the lowest slot set in a bitmask, and the bitmask
+// with it cleared.
+func swissNext(m uint64) (int, uint64) {
+	return bits.TrailingZeros64(m) / 8, m & (m - 1)
+}
+
+func (t *swissTable) find(key Hasher, h uint64) *item {
+	h1, h2 := swissHash(h)
+	mask := uint64(len(t.groups) - 1)
+	g := h1 & mask
+	for step := uint64(1); step <= uint64(len(t.groups)); step++ {
+		group := &t.groups[g]
+		w := group.word()
+		for m := swissMatch(w, h2); m != 0; {
+			var s int
+			s, m = swissNext(m)
+			if i := group.items[s]; i != nil && i.hash == h && key.Equal(i.key) {
+				return i
+			}
+		}
+		if swissMatchEmpty(w) != 0 {
+			return nil
+		}
+		g = (g + step) & mask
+	}
+	return nil
+}
+
+func (t *swissTable) insert(i *item) {
+	// keep the table at most 7/8 full, counting deleted slots.
+	if (t.used+1)*8 > len(t.groups)*swissGroupSize*7 {
+		t.resize()
+	}
+	h1, h2 := swissHash(i.hash)
+	mask := uint64(len(t.groups) - 1)
+	g := h1 & mask
+	for step := uint64(1); ; step++ {
+		group := &t.groups[g]
+		if m := swissMatchFree(group.word()); m != 0 {
+			s, _ := swissNext(m)
+			if group.ctrl[s] == swissEmpty {
+				t.used++
+			}
+			group.ctrl[s] = h2
+			group.items[s] = i
+			t.count++
+			return
+		}
+		g = (g + step) & mask
+	}
+}
+
+func (t *swissTable) delete(i *item) bool {
+	h1, h2 := swissHash(i.hash)
+	mask := uint64(len(t.groups) - 1)
+	g := h1 & mask
+	for step := uint64(1); step <= uint64(len(t.groups)); step++ {
+		group := &t.groups[g]
+		w := group.word()
+		for m := swissMatch(w, h2); m != 0; {
+			var s int
+			s, m = swissNext(m)
+			if group.items[s] != i {
+				continue
+			}
+			group.items[s] = nil
+			t.count--
+			// a lookup stops at a group with an empty slot, so no probe
+			// sequence continues past this group and the slot can be
+			// reused right away.
+			if swissMatchEmpty(w) != 0 {
+				group.ctrl[s] = swissEmpty
+				t.used--
+			} else {
+				group.ctrl[s] = swissDeleted
+			}
+			return true
+		}
+		if swissMatchEmpty(w) != 0 {
+			return false
+		}
+		g = (g + step) & mask
+	}
+	return false
+}
+
+// resize rehashes the items into new groups. The table only doubles if it is
+// at least half full of live items; otherwise, clearing the deleted slots is
+// enough.
+func (t *swissTable) resize() {
+	old := t.groups
+	size := len(old)
+	if t.count*2 >= size*swissGroupSize {
+		size <<= 1
+	}
+	t.groups = newSwissGroups(size)
+	t.used = 0
+	t.count = 0
+	for g := range old {
+		for _, i := range old[g].items {
+			if i != nil {
+				t.insert(i)
+			}
+		}
+	}
+}
+
+func (t *swissTable) capacity() int {
+	return len(t.groups) * swissGroupSize * 7 / 8
+}
+
+// each group probed counts as one step of a chain.
+func (t *swissTable) stats(s *Stats) {
+	s.Buckets += len(t.groups) * swissGroupSize
+	mask := uint64(len(t.groups) - 1)
+	for _, group := range t.groups {
+		for _, i := range group.items {
+			if i == nil {
+				s.EmptyBuckets++
+				continue
+			}
+			h1, _ := swissHash(i.hash)
+			g := h1 & mask
+			for step := uint64(1); ; step++ {
+				if t.groups[g].contains(i) {
+					s.record(int(step))
+					break
+				}
+				g = (g + step) & mask
+			}
+		}
+	}
+}
+
+// the bucket is the first slot of the group the probe starts at.
+func (t *swissTable) chain(i *item) (int, int) {
+	h1, _ := swissHash(i.hash)
+	mask := uint64(len(t.groups) - 1)
+	start := h1 & mask
+	g := start
+	for step := uint64(1); step <= uint64(len(t.groups)); step++ {
+		if t.groups[g].contains(i) {
+			return int(start) * swissGroupSize, int(step)
+		}
+		g = (g + step) & mask
+	}
+	return int(start) * swissGroupSize, 0
+}
+
+func (g *swissGroup) contains(i *item) bool {
+	for _, v := range g.items {
+		if v == i {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *swissTable) layout(f func(items []*item)) {
+	for g := range t.groups {
+		for _, i := range t.groups[g].items {
+			if i == nil {
+				f(nil)
+			} else {
+				f([]*item{i})
+			}
+		}
+	}
+}
+
+func (t *swissTable) cursor() cursor {
+	return &swissCursor{t: t}
+}
+
+type swissCursor struct {
+	t *swissTable
+	// the next slot to look at, counting across groups.
+	pos int
+}
+
+// deleting an item only changes its own slot, so the remaining slots are not
+// disturbed.
+func (c *swissCursor) next() *item {
+	for c.pos < len(c.t.groups)*swissGroupSize {
+		i := c.t.groups[c.pos/swissGroupSize].items[c.pos%swissGroupSize]
+		c.pos++
+		if i != nil {
+			return i
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package dictionary
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type (
+	// ValueParser converts the text of a value read by LoadFrom.
+	ValueParser func(string) (interface{}, error)
+
+	// ValueFormatter converts a value to text for WriteTo.
+	ValueFormatter func(interface{}) (string, error)
+)
+
+// the separator used by LoadFrom and WriteTo unless WithTextFormat sets
+// another.
+const defaultTextSeparator = "="
+
+// WithTextFormat sets how LoadFrom and WriteTo read and write the text form of
+// a dictionary. Each line holds a key, the separator, and a value. By default,
+// the separator is "=", and values are kept as strings when read and written
+// using fmt.Sprint. An empty separator or nil function keeps the default.
+// Keys are converted using the functions set by SetKeyEncoding, as for JSON.
+func WithTextFormat(separator string, parse ValueParser, format ValueFormatter) OptionsFunc {
+	return func(d *Dictionary) {
+		d.textSeparator = separator
+		d.parseValue = parse
+		d.formatValue = format
+	}
+}
+
+func (d *Dictionary) separator() string {
+	if d.textSeparator != "" {
+		return d.textSeparator
+	}
+	return defaultTextSeparator
+}
+
+// LoadFrom creates a dictionary from lines of text, such as a small lookup
+// table kept in a file. Each line holds a key and a value, split at the first
+// separator, with spaces around both trimmed. Blank lines and lines starting
+// with # are skipped. Options are passed to New, and WithTextFormat sets the
+// separator and how values are parsed.
+func LoadFrom(r io.Reader, options ...OptionsFunc) (*Dictionary, error) {
+	d := New(options...)
+	sep := d.separator()
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(text, sep)
+		if !ok {
+			return nil, fmt.Errorf("dictionary: line %d: missing separator %q", line, sep)
+		}
+
+		key, err := d.decodeKey(strings.TrimSpace(k))
+		if err != nil {
+			return nil, fmt.Errorf("dictionary: line %d: %w", line, err)
+		}
+		var val interface{} = strings.TrimSpace(v)
+		if d.parseValue != nil {
+			if val, err = d.parseValue(val.(string)); err != nil {
+				return nil, fmt.Errorf("dictionary: line %d: %w", line, err)
+			}
+		}
+		d.Set(key, val)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// WriteTo writes the dictionary as lines of text that LoadFrom can read, in
+// order of key, so the output is stable. It returns the number of bytes
+// written. Keys that contain the separator, and keys or values that contain
+// a line break or would be changed by trimming spaces, cannot be read back,
+// so they are an error. w is written to once for each line, so callers may
+// want to wrap it in a bufio.Writer.
+func (d *Dictionary) WriteTo(w io.Writer) (int64, error) {
+	sep := d.separator()
+	type line struct {
+		key, text string
+	}
+	lines := make([]line, 0, d.Len())
+	var err error
+	d.walk(func(i *item) bool {
+		var k, v string
+		if k, err = d.encodeKey(i.key); err != nil {
+			return false
+		}
+		if d.formatValue != nil {
+			v, err = d.formatValue(i.value)
+		} else {
+			v = fmt.Sprint(i.value)
+		}
+		if err != nil {
+			return false
+		}
+		switch {
+		case strings.Contains(k, sep):
+			err = fmt.Errorf("dictionary: key %q contains the separator %q", k, sep)
+		case !textSafe(k) || strings.HasPrefix(k, "#"):
+			err = fmt.Errorf("dictionary: key %q cannot be written as text", k)
+		case !textSafe(v):
+			err = fmt.Errorf("dictionary: value %q for key %q cannot be written as text", v, k)
+		}
+		lines = append(lines, line{key: k, text: k + sep + v + "\n"})
+		return err == nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(lines, func(a, b int) bool { return lines[a].key < lines[b].key })
+
+	var n int64
+	for _, l := range lines {
+		written, err := io.WriteString(w, l.text)
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// textSafe reports whether s would be read back unchanged by LoadFrom.
+func textSafe(s string) bool {
+	return !strings.ContainsAny(s, "\r\n") && strings.TrimSpace(s) == s
+}
@@ -0,0 +1,103 @@
+package dictionary
+
+// frequencySketch estimates how often each hash has been seen, using a
+// count-min sketch: each hash increments one counter in each of several rows,
+// and the smallest of those counters is the estimate. Collisions can only
+// make an estimate too high. Counters are halved periodically, so the
+// estimates favor recent activity.
+type frequencySketch struct {
+	rows [sketchDepth][]uint8
+	mask uint64
+	// increments since the counters were last halved.
+	additions int
+	resetAt   int
+}
+
+const (
+	sketchDepth = 4
+	// counters saturate here, as only the relative frequency of popular
+	// keys matters.
+	sketchMax = 15
+)
+
+// different for each row, so the rows are independent.
+var sketchSeeds = [sketchDepth]uint64{
+	0x9e3779b97f4a7c15, 0xbf58476d1ce4e5b9, 0x94d049bb133111eb, 0xc2b2ae3d27d4eb4f,
+}
+
+// the sketch for n entries has a few counters per entry, so keys that are
+// not present rarely share all of their counters with popular ones.
+func newFrequencySketch(n int) *frequencySketch {
+	width := 64
+	for width < 4*n {
+		width <<= 1
+	}
+	s := &frequencySketch{
+		mask: uint64(width - 1),
+		// as in the TinyLFU paper, age the counters after ten times as
+		// many uses as there are entries.
+		resetAt: 10 * n,
+	}
+	for r := range s.rows {
+		s.rows[r] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *frequencySketch) increment(h uint64) {
+	for r := range s.rows {
+		c := &s.rows[r][mix(h^sketchSeeds[r])&s.mask]
+		if *c < sketchMax {
+			*c++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		for r := range s.rows {
+			for n := range s.rows[r] {
+				s.rows[r][n] /= 2
+			}
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *frequencySketch) estimate(h uint64) uint8 {
+	min := uint8(sketchMax)
+	for r := range s.rows {
+		if c := s.rows[r][mix(h^sketchSeeds[r])&s.mask]; c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// WithTinyLFU adds a TinyLFU admission filter to a dictionary bounded by
+// SetMaxEntries. The dictionary keeps an estimate of how often each key is
+// set or looked up, including keys that are not present. When a key is added
+// to a full dictionary, it is only admitted if it has been used more often
+// than the entry that would be evicted for it; otherwise, it is dropped. This
+// keeps keys that are seen once, such as during a scan, from pushing out
+// popular entries.
+func WithTinyLFU() OptionsFunc {
+	return func(d *Dictionary) {
+		d.tinyLFU = true
+	}
+}
+
+// helper to record a use of a key, if there is an admission filter.
+func (d *Dictionary) recordAccess(h uint64) {
+	if d.sketch != nil {
+		d.sketch.increment(h)
+	}
+}
+
+// helper to decide whether a new item should be added. Items are always
+// admitted unless the dictionary is full and has an admission filter.
+func (d *Dictionary) admit(i *item) bool {
+	if d.sketch == nil || d.count < d.maxEntries {
+		return true
+	}
+	victim := d.policy.Evictee()
+	return d.sketch.estimate(i.hash) > d.sketch.estimate(d.hash(victim))
+}
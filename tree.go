@@ -0,0 +1,189 @@
+package dictionary
+
+type (
+	// treeBucket is a bucket backed by an AVL tree, used for buckets with
+	// many colliding keys. Items are ordered by hash, and then by key, so
+	// all of the keys must implement Ordered.
+	treeBucket struct {
+		root  *treeNode
+		count int
+	}
+
+	treeNode struct {
+		item        *item
+		left, right *treeNode
+		height      int
+	}
+)
+
+// newTreeBucket returns a tree holding items, or nil if any of the keys do not
+// implement Ordered.
+func newTreeBucket(items []*item) *treeBucket {
+	b := &treeBucket{}
+	for _, i := range items {
+		if _, ok := i.key.(Ordered); !ok {
+			return nil
+		}
+		b.insert(i)
+	}
+	return b
+}
+
+// compareItem returns -1, 0 or 1 as key sorts before, the same as, or after
+// the item.
+func compareItem(key Hasher, h uint64, i *item) int {
+	switch {
+	case h < i.hash:
+		return -1
+	case h > i.hash:
+		return 1
+	case key.(Ordered).Less(i.key):
+		return -1
+	case i.key.(Ordered).Less(key):
+		return 1
+	}
+	return 0
+}
+
+func (b *treeBucket) find(key Hasher, h uint64) *item {
+	if _, ok := key.(Ordered); !ok {
+		return nil
+	}
+	n := b.root
+	for n != nil {
+		switch compareItem(key, h, n.item) {
+		case -1:
+			n = n.left
+		case 1:
+			n = n.right
+		default:
+			if key.Equal(n.item.key) {
+				return n.item
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+func (b *treeBucket) insert(i *item) {
+	b.root = b.root.insert(i)
+	b.count++
+}
+
+func (b *treeBucket) delete(i *item) bool {
+	var found bool
+	b.root, found = b.root.delete(i)
+	if found {
+		b.count--
+	}
+	return found
+}
+
+func (b *treeBucket) len() int {
+	return b.count
+}
+
+func (b *treeBucket) appendItems(dst []*item) []*item {
+	return b.root.appendItems(dst)
+}
+
+func (n *treeNode) appendItems(dst []*item) []*item {
+	if n == nil {
+		return dst
+	}
+	dst = n.left.appendItems(dst)
+	dst = append(dst, n.item)
+	return n.right.appendItems(dst)
+}
+
+func (n *treeNode) getHeight() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func (n *treeNode) update() {
+	n.height = 1 + max(n.left.getHeight(), n.right.getHeight())
+}
+
+func (n *treeNode) rotateLeft() *treeNode {
+	r := n.right
+	n.right = r.left
+	r.left = n
+	n.update()
+	r.update()
+	return r
+}
+
+func (n *treeNode) rotateRight() *treeNode {
+	l := n.left
+	n.left = l.right
+	l.right = n
+	n.update()
+	l.update()
+	return l
+}
+
+// balance restores the AVL property at n, and returns the new root of the
+// subtree.
+func (n *treeNode) balance() *treeNode {
+	n.update()
+	switch d := n.left.getHeight() - n.right.getHeight(); {
+	case d > 1:
+		if n.left.left.getHeight() < n.left.right.getHeight() {
+			n.left = n.left.rotateLeft()
+		}
+		return n.rotateRight()
+	case d < -1:
+		if n.right.right.getHeight() < n.right.left.getHeight() {
+			n.right = n.right.rotateRight()
+		}
+		return n.rotateLeft()
+	}
+	return n
+}
+
+// insert adds an item known not to be in the tree, and returns the new root.
+func (n *treeNode) insert(i *item) *treeNode {
+	if n == nil {
+		return &treeNode{item: i, height: 1}
+	}
+	if compareItem(i.key, i.hash, n.item) < 0 {
+		n.left = n.left.insert(i)
+	} else {
+		n.right = n.right.insert(i)
+	}
+	return n.balance()
+}
+
+// delete removes the item, and returns the new root and whether it was found.
+func (n *treeNode) delete(i *item) (*treeNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+	var found bool
+	switch c := compareItem(i.key, i.hash, n.item); {
+	case c < 0:
+		n.left, found = n.left.delete(i)
+	case c > 0:
+		n.right, found = n.right.delete(i)
+	case n.item != i:
+		return n, false
+	case n.left == nil:
+		return n.right, true
+	case n.right == nil:
+		return n.left, true
+	default:
+		// replace the item with the smallest one in the right subtree.
+		m := n.right
+		for m.left != nil {
+			m = m.left
+		}
+		n.item = m.item
+		n.right, _ = n.right.delete(m.item)
+		found = true
+	}
+	return n.balance(), found
+}
@@ -0,0 +1,63 @@
+package dictionary
+
+import "time"
+
+// SetWithTTL adds an item to the dictionary that expires after ttl. Once
+// expired, the entry is treated as missing and is removed the next time it
+// is come across. It will replace any existing value and expiration.
+func (d *Dictionary) SetWithTTL(key Hasher, val interface{}, ttl time.Duration) {
+	d.set(key, val, d.now().Add(ttl), ttl)
+}
+
+// WithSlidingExpiration renews the expiration time of an entry set with
+// SetWithTTL whenever it is read, such as by Get, so it expires only once it
+// has gone unused for its TTL. This is useful for idle timeouts, such as for
+// sessions.
+func WithSlidingExpiration() OptionsFunc {
+	return func(d *Dictionary) {
+		d.sliding = true
+	}
+}
+
+// withClock sets the function used to get the current time when setting and
+// checking expiration times, rather than time.Now. It is only for tests, which
+// can then expire entries by moving the clock forward rather than sleeping.
+func withClock(now func() time.Time) OptionsFunc {
+	return func(d *Dictionary) {
+		d.clock = now
+	}
+}
+
+// helper to get the current time, from the clock if there is one.
+func (d *Dictionary) now() time.Time {
+	if d.clock != nil {
+		return d.clock()
+	}
+	return time.Now()
+}
+
+// helper to renew the expiration time of an item that has been used, if
+// sliding expiration is enabled.
+func (d *Dictionary) renew(i *item) {
+	if d.sliding && i.ttl > 0 {
+		i.expires = d.now().Add(i.ttl)
+	}
+}
+
+// helper to change the expiration time of an item already in the dictionary.
+func (d *Dictionary) setExpires(i *item, expires time.Time, ttl time.Duration) {
+	if !i.expires.IsZero() {
+		d.expiring--
+	}
+	if !expires.IsZero() {
+		d.expiring++
+	}
+	i.expires = expires
+	i.ttl = ttl
+}
+
+// expired reports whether the item has expired at now. Items without an
+// expiration time never expire.
+func (i *item) expired(now time.Time) bool {
+	return !i.expires.IsZero() && !now.Before(i.expires)
+}
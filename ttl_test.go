@@ -0,0 +1,147 @@
+package dictionary_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bakins/dictionary"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock for WithClock that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestTTL(t *testing.T) {
+	clock := newFakeClock()
+	d := dictionary.New(dictionary.WithClock(clock.Now))
+	a := dictionary.StringKey("a")
+	b := dictionary.StringKey("b")
+	c := dictionary.StringKey("c")
+
+	d.SetWithTTL(a, 1, time.Millisecond)
+	d.SetWithTTL(b, 2, time.Hour)
+	d.Set(c, 3)
+	require.Equal(t, 3, d.Len(), "unexpected length")
+
+	clock.Advance(time.Millisecond)
+
+	v, ok := d.Get(a)
+	require.Nil(t, v)
+	require.Equal(t, false, ok, "should not have found expired key")
+
+	_, ok = d.Get(b)
+	require.Equal(t, true, ok, "should have found key")
+	require.Equal(t, 2, d.Len(), "unexpected length")
+	require.Equal(t, 2, len(d.Keys()), "unexpected number of keys")
+
+	// setting without a TTL clears the expiration.
+	d.SetWithTTL(a, 1, time.Millisecond)
+	d.Set(a, 1)
+	clock.Advance(time.Millisecond)
+	require.Equal(t, true, d.Contains(a), "should have found key")
+
+	d.SetWithTTL(a, 1, time.Millisecond)
+	clock.Advance(time.Millisecond)
+
+	_, ok = d.Delete(a)
+	require.Equal(t, false, ok, "should not have deleted expired key")
+
+	d.SetWithTTL(a, 1, time.Millisecond)
+	clock.Advance(time.Millisecond)
+
+	err := d.Each(func(k dictionary.Hasher, v interface{}) error {
+		require.NotEqual(t, a, k, "should not have seen expired key")
+		return nil
+	})
+	require.Nil(t, err)
+
+	d.SetWithTTL(a, 1, time.Millisecond)
+	clock.Advance(time.Millisecond)
+
+	it := d.Iterator()
+	n := 0
+	for it.Next() {
+		n++
+	}
+	require.Equal(t, 2, n, "unexpected number of entries")
+}
+
+func TestSlidingExpiration(t *testing.T) {
+	clock := newFakeClock()
+	d := dictionary.New(dictionary.WithSlidingExpiration(), dictionary.WithClock(clock.Now))
+	a := dictionary.StringKey("a")
+	b := dictionary.StringKey("b")
+
+	d.SetWithTTL(a, 1, 50*time.Millisecond)
+	d.SetWithTTL(b, 2, 50*time.Millisecond)
+
+	// reading a keeps it alive, while b goes unused.
+	for n := 0; n < 5; n++ {
+		clock.Advance(20 * time.Millisecond)
+		_, ok := d.Get(a)
+		require.Equal(t, true, ok, "should have found key")
+	}
+	require.Equal(t, false, d.Contains(b), "unused key should have expired")
+
+	clock.Advance(49 * time.Millisecond)
+	require.Equal(t, true, d.Contains(a), "key should not have expired yet")
+	clock.Advance(time.Millisecond)
+	require.Equal(t, false, d.Contains(a), "key should expire once unused")
+}
+
+func TestJanitor(t *testing.T) {
+	clock := newFakeClock()
+	removed := make(chan dictionary.Hasher, 100)
+	d := dictionary.New(
+		dictionary.WithJanitor(time.Millisecond),
+		dictionary.WithClock(clock.Now),
+		dictionary.WithOnDelete(func(k dictionary.Hasher, _ interface{}) {
+			removed <- k
+		}),
+	)
+	defer d.Close()
+
+	d.Lock()
+	for i := 0; i < 100; i++ {
+		d.SetWithTTL(dictionary.StringKey(strconv.Itoa(i)), i, time.Second)
+	}
+	d.Set(dictionary.StringKey("foo"), "bar")
+	clock.Advance(time.Second)
+	d.Unlock()
+
+	// only the janitor looks at the entries, so it removed them.
+	for i := 0; i < 100; i++ {
+		select {
+		case k := <-removed:
+			require.NotEqual(t, dictionary.StringKey("foo"), k, "should not have removed key")
+		case <-time.After(5 * time.Second):
+			t.Fatal("janitor did not remove expired entries")
+		}
+	}
+
+	require.Nil(t, d.Close())
+	require.Nil(t, d.Close())
+
+	// Close does not wait for a janitor that is waiting for the lock.
+	d = dictionary.New(dictionary.WithJanitor(time.Millisecond))
+	d.Lock()
+	// long enough for the janitor to tick and wait for the lock.
+	time.Sleep(5 * time.Millisecond)
+	require.Nil(t, d.Close())
+	d.Unlock()
+}
@@ -0,0 +1,67 @@
+package dictionary
+
+// Txn buffers changes to a dictionary, so they can be applied all at once. It
+// is passed to the function given to Dictionary.Txn.
+type Txn struct {
+	d   *Dictionary
+	ops []txnOp
+	// the latest change for each key, so reads see earlier writes.
+	pending *Dictionary
+}
+
+type txnOp struct {
+	key    Hasher
+	value  interface{}
+	delete bool
+}
+
+// Txn calls f with a transaction, and applies the changes made through it
+// only if f returns nil. Otherwise, they are discarded and the error is
+// returned. Like other methods, Txn does not lock the dictionary; a caller
+// holding the lock for the whole of Txn ensures that other goroutines using
+// Lock never see it half applied. f must not use the dictionary directly.
+func (d *Dictionary) Txn(f func(tx *Txn) error) error {
+	tx := &Txn{
+		d: d,
+		// keys are looked up in the pending changes as they would be in
+		// d, but only a few are expected, so it starts small.
+		pending: New(SetBuckets(8), SetHashSeed(d.seed), WithStringHash(d.stringHash), WithKeyNormalizer(d.normalizer), WithBackend(d.backend)),
+	}
+	if err := f(tx); err != nil {
+		return err
+	}
+	for _, op := range tx.ops {
+		if op.delete {
+			d.Delete(op.key)
+		} else {
+			d.Set(op.key, op.value)
+		}
+	}
+	return nil
+}
+
+// Get returns an item as it would be if the transaction were applied. The
+// second return value will be false if not found. Missing entries are never
+// created by a default factory.
+func (tx *Txn) Get(key Hasher) (interface{}, bool) {
+	if v, ok := tx.pending.Get(key); ok {
+		op := v.(txnOp)
+		return op.value, !op.delete
+	}
+	return tx.d.ReadOnly().Get(key)
+}
+
+// Set sets the value for key when the transaction is applied.
+func (tx *Txn) Set(key Hasher, val interface{}) {
+	tx.record(txnOp{key: key, value: val})
+}
+
+// Delete removes key when the transaction is applied.
+func (tx *Txn) Delete(key Hasher) {
+	tx.record(txnOp{key: key, delete: true})
+}
+
+func (tx *Txn) record(op txnOp) {
+	tx.ops = append(tx.ops, op)
+	tx.pending.Set(op.key, op)
+}
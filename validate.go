@@ -0,0 +1,76 @@
+package dictionary
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidBucketCount is returned by NewWithError when the number of
+	// buckets is set to zero.
+	ErrInvalidBucketCount = errors.New("dictionary: number of buckets must be greater than zero")
+	// ErrInvalidOption is returned by NewWithError when an option is out of
+	// range. The error returned wraps it with the details.
+	ErrInvalidOption = errors.New("dictionary: invalid option")
+	// ErrNilKey is the value of the panic when a nil key is used.
+	ErrNilKey = errors.New("dictionary: nil key")
+)
+
+// NewWithError creates a new dictionary, like New, but returns an error if
+// any of the options are invalid, rather than replacing them with defaults or
+// failing when the dictionary is used.
+func NewWithError(options ...OptionsFunc) (*Dictionary, error) {
+	d := newDictionary(options)
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+	d.start()
+	return d, nil
+}
+
+// MustNew creates a new dictionary, like NewWithError, but panics if any of
+// the options are invalid. It is meant for package level variables and
+// options that are known to be valid.
+func MustNew(options ...OptionsFunc) *Dictionary {
+	d, err := NewWithError(options...)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// helper to check the options, once they have been applied.
+func (d *Dictionary) validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidOption, fmt.Sprintf(format, args...))
+	}
+	switch {
+	case d.numBuckets == 0:
+		return ErrInvalidBucketCount
+	case d.backend < Chaining || d.backend > GoMap:
+		return invalid("unknown backend %d", d.backend)
+	case d.bucketFactory != nil && d.backend != Chaining:
+		return invalid("WithBucketFactory requires the Chaining backend")
+	case d.order < BucketOrder || d.order > InsertionOrder:
+		return invalid("unknown order %d", d.order)
+	case d.capacity < 0:
+		return invalid("negative capacity %d", d.capacity)
+	case d.maxEntries < 0:
+		return invalid("negative maximum entries %d", d.maxEntries)
+	case d.maxWeight < 0:
+		return invalid("negative maximum weight %d", d.maxWeight)
+	case d.tinyLFU && d.maxEntries == 0:
+		return invalid("WithTinyLFU requires SetMaxEntries")
+	case d.maxWeight > 0 && d.weigher == nil:
+		return invalid("SetMaxWeight requires WithWeigher")
+	case d.shrinkFraction < 0 || d.shrinkFraction >= 1:
+		return invalid("auto shrink fraction %v is not between 0 and 1", d.shrinkFraction)
+	case d.chainWarning != nil && d.chainThreshold < 1:
+		return invalid("chain length warning threshold %d is less than 1", d.chainThreshold)
+	case d.bloomItems < 0 || (d.bloomItems > 0 && (d.bloomRate <= 0 || d.bloomRate >= 1)):
+		return invalid("Bloom filter for %d items with false positive rate %v", d.bloomItems, d.bloomRate)
+	case d.janitorInterval < 0:
+		return invalid("negative janitor interval %v", d.janitorInterval)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package dictionary
+
+// GetValue returns the value for key as a V, for callers that know the type
+// of the values, without the type assertion Get would need. The second return
+// value will be false if the key is not found, or if its value is not a V.
+//
+// Neither Get nor GetValue allocate. Converting a key to a Hasher may
+// allocate, such as for a StringKey, so in hot paths keys should be converted
+// once and reused, or be pointers.
+func GetValue[V any](d *Dictionary, key Hasher) (V, bool) {
+	v, ok := d.Get(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	val, ok := v.(V)
+	return val, ok
+}
@@ -0,0 +1,87 @@
+package dictionary
+
+import "errors"
+
+// ErrUnknownVersion is returned by Versioned when asked for a version that
+// does not exist.
+var ErrUnknownVersion = errors.New("dictionary: unknown version")
+
+// Versioned is a dictionary that keeps its history. Every change creates a
+// new version, numbered one more than the last, and any earlier version can be
+// read or rolled back to. The versions are Persistent dictionaries, so they
+// share most of their structure and history is cheap to keep.
+//
+// Like Dictionary, a Versioned is not safe for concurrent use, but the
+// Persistent dictionaries returned by At and Current never change and may be
+// read from any goroutine.
+type Versioned struct {
+	// versions[n] is version n. Version 0 is empty.
+	versions []*Persistent
+}
+
+// NewVersioned creates an empty Versioned dictionary, at version 0. Options are
+// passed to NewPersistent.
+func NewVersioned(options ...OptionsFunc) *Versioned {
+	return &Versioned{
+		versions: []*Persistent{NewPersistent(options...)},
+	}
+}
+
+// Version returns the current version.
+func (v *Versioned) Version() uint64 {
+	return uint64(len(v.versions) - 1)
+}
+
+// Current returns the entries at the current version.
+func (v *Versioned) Current() *Persistent {
+	return v.versions[len(v.versions)-1]
+}
+
+// helper to record a new version, if it differs from the current one.
+func (v *Versioned) commit(p *Persistent) uint64 {
+	if p != v.Current() {
+		v.versions = append(v.versions, p)
+	}
+	return v.Version()
+}
+
+// Get returns an item at the current version. The second return value will
+// be false if not found.
+func (v *Versioned) Get(key Hasher) (interface{}, bool) {
+	return v.Current().Get(key)
+}
+
+// Len returns the number of entries at the current version.
+func (v *Versioned) Len() int {
+	return v.Current().Len()
+}
+
+// Set sets the value for key, and returns the new version.
+func (v *Versioned) Set(key Hasher, val interface{}) uint64 {
+	return v.commit(v.Current().Set(key, val))
+}
+
+// Delete removes key, and returns the new version. Deleting a key that is not
+// present does not create a version.
+func (v *Versioned) Delete(key Hasher) uint64 {
+	return v.commit(v.Current().Delete(key))
+}
+
+// At returns the entries as they were at version. ErrUnknownVersion is
+// returned if version is later than the current version.
+func (v *Versioned) At(version uint64) (*Persistent, error) {
+	if version > v.Version() {
+		return nil, ErrUnknownVersion
+	}
+	return v.versions[version], nil
+}
+
+// Rollback restores the entries as they were at version. The history is kept:
+// the rollback is recorded as a new version, which is returned.
+func (v *Versioned) Rollback(version uint64) (uint64, error) {
+	p, err := v.At(version)
+	if err != nil {
+		return 0, err
+	}
+	return v.commit(p), nil
+}
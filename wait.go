@@ -0,0 +1,33 @@
+package dictionary
+
+import "context"
+
+// GetWait returns the value for key, waiting until another goroutine sets it
+// if it is not present. It returns ctx.Err() if ctx is done first. Unlike Get,
+// the default factory is not used to create missing entries.
+//
+// Like sync.Cond.Wait, GetWait is called with the dictionary's lock held. It
+// unlocks the dictionary while waiting, so the goroutines setting keys can
+// take the lock, and locks it again before returning.
+func (d *Dictionary) GetWait(ctx context.Context, key Hasher) (interface{}, error) {
+	if _, _, i := d.lookup(key); i != nil {
+		d.touch(i)
+		return i.value, nil
+	}
+	// start watching before unlocking, so a set in between is not missed.
+	events, cancel := d.Watch(key)
+	d.Unlock()
+	defer d.Lock()
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case e := <-events:
+			if e.Type == EventSet {
+				return e.Value, nil
+			}
+		}
+	}
+}
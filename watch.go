@@ -0,0 +1,139 @@
+package dictionary
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// EventType is the kind of change described by an Event.
+	EventType int
+
+	// Event describes a change to an entry, as delivered by Watch.
+	Event struct {
+		Type EventType
+		Key  Hasher
+		// Value is the new value for EventSet, and the removed value for
+		// EventDelete.
+		Value interface{}
+	}
+
+	// watcher queues events for a channel returned by Watch. Events are
+	// queued rather than sent directly, so a slow receiver never blocks
+	// changes to the dictionary.
+	watcher struct {
+		// nil to watch all keys.
+		key Hasher
+		// the hash of key, so most changes to other keys are ruled out
+		// without calling Equal.
+		hash uint64
+		ch   chan Event
+
+		mu    sync.Mutex
+		queue []Event
+		// signaled when events are queued.
+		wake chan struct{}
+		stop chan struct{}
+	}
+)
+
+const (
+	// EventSet is sent when an entry is added or its value replaced.
+	EventSet EventType = iota
+	// EventDelete is sent when an entry is removed, including when it is
+	// evicted or expires.
+	EventDelete
+)
+
+// Watch returns a channel that receives an Event for each change to key, or
+// to every key if key is nil. Events are queued without limit until they are
+// received, so changing the dictionary never blocks. The returned function
+// stops the watch and closes the channel; it must be called to release the
+// resources used. Watch and the returned function may be called without
+// holding the dictionary's lock.
+func (d *Dictionary) Watch(key Hasher) (<-chan Event, func()) {
+	w := &watcher{
+		key:  d.normalize(key),
+		ch:   make(chan Event),
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+	}
+
+	if w.key != nil {
+		w.hash = d.hash(w.key)
+	}
+
+	d.watchMu.Lock()
+	if d.watchers == nil {
+		d.watchers = make(map[*watcher]struct{})
+	}
+	d.watchers[w] = struct{}{}
+	atomic.AddInt32(&d.numWatchers, 1)
+	d.watchMu.Unlock()
+
+	go w.run()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			d.watchMu.Lock()
+			delete(d.watchers, w)
+			atomic.AddInt32(&d.numWatchers, -1)
+			d.watchMu.Unlock()
+			close(w.stop)
+		})
+	}
+	return w.ch, cancel
+}
+
+// helper to report whether there are any watchers, without taking the lock.
+func (d *Dictionary) watched() bool {
+	return atomic.LoadInt32(&d.numWatchers) > 0
+}
+
+// helper to send an event to the watchers of the key of i. Keys are only
+// compared with Equal if their hashes match and they are of the same type, as
+// Equal may assume its argument is of its own type.
+func (d *Dictionary) notify(t EventType, i *item, val interface{}) {
+	d.watchMu.Lock()
+	defer d.watchMu.Unlock()
+
+	for w := range d.watchers {
+		if w.key != nil && (w.hash != i.hash || reflect.TypeOf(w.key) != reflect.TypeOf(i.key) || !w.key.Equal(i.key)) {
+			continue
+		}
+		w.mu.Lock()
+		w.queue = append(w.queue, Event{Type: t, Key: i.key, Value: val})
+		w.mu.Unlock()
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run delivers queued events until the watch is stopped.
+func (w *watcher) run() {
+	defer close(w.ch)
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.wake:
+		}
+
+		w.mu.Lock()
+		events := w.queue
+		w.queue = nil
+		w.mu.Unlock()
+
+		for _, e := range events {
+			select {
+			case w.ch <- e:
+			case <-w.stop:
+				return
+			}
+		}
+	}
+}